@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"errors"
+	"go_redis/internals/resp"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	errTimeoutNotFloat = errors.New("timeout is not a float or out of range")
+	errTimeoutNegative = errors.New("timeout is negative")
+)
+
+// Block is returned by Execute when a blocking command found nothing to
+// serve. The caller parks the client on Keys and executes the command again
+// once one of them receives elements, replying with WriteTimeout if Timeout
+// elapses first. A zero Timeout blocks forever.
+type Block struct {
+	Keys    []string
+	Timeout time.Duration
+	timeout func(w *resp.Writer)
+}
+
+// WriteTimeout writes the reply the command gives when it times out, which
+// is also its reply when it cannot block, e.g. inside MULTI.
+func (b *Block) WriteTimeout(w *resp.Writer) {
+	b.timeout(w)
+}
+
+// parseTimeout parses a timeout in seconds, which may be fractional.
+func parseTimeout(s string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(seconds) || seconds > math.MaxInt64/float64(time.Second) {
+		return 0, errTimeoutNotFloat
+	}
+	if seconds < 0 {
+		return 0, errTimeoutNegative
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
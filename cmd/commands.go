@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// arities holds the number of arguments each command takes, its name
+// included, as in Redis: n means exactly n and -n at least n. It lists the
+// commands the server handles itself too, so a transaction can reject
+// anything malformed while it is being queued.
+var arities = map[string]int{
+	// Connection, transactions and pub/sub, handled by the server.
+	"HELLO": -1, "QUIT": -1,
+	"MULTI": 1, "EXEC": 1, "DISCARD": 1, "WATCH": -2, "UNWATCH": 1,
+	"SUBSCRIBE": -2, "PSUBSCRIBE": -2, "UNSUBSCRIBE": -1, "PUNSUBSCRIBE": -1,
+	"PUBLISH": 3, "PUBSUB": -2,
+
+	"PING": -1,
+
+	// Strings
+	"SET": -3, "GET": 2, "SETNX": 3, "SETEX": 4, "PSETEX": 4, "GETSET": 3,
+	"GETDEL": 2, "GETEX": -2, "MSET": -3, "MSETNX": -3, "MGET": -2,
+	"INCR": 2, "DECR": 2, "INCRBY": 3, "DECRBY": 3, "INCRBYFLOAT": 3,
+	"APPEND": 3, "STRLEN": 2, "GETRANGE": 4, "SETRANGE": 4, "LCS": -3,
+
+	// Hashes
+	"HSET": -4, "HGET": 3, "HGETALL": 2, "HSETNX": 4, "HMGET": -3, "HDEL": -3,
+	"HEXISTS": 3, "HLEN": 2, "HSTRLEN": 3, "HKEYS": 2, "HVALS": 2,
+	"HINCRBY": 4, "HINCRBYFLOAT": 4, "HRANDFIELD": -2,
+	"HEXPIRE": -6, "HPEXPIRE": -6, "HEXPIREAT": -6, "HPEXPIREAT": -6,
+	"HTTL": -5, "HPTTL": -5, "HPERSIST": -5,
+
+	// Keyspace
+	"DEL": -2, "EXISTS": -2, "TYPE": 2, "KEYS": 2, "SCAN": -2,
+	"RENAME": 3, "RENAMENX": 3, "COPY": -3, "MOVE": 3, "SELECT": 2, "SWAPDB": 3,
+	"RANDOMKEY": 1, "DBSIZE": 1, "FLUSHDB": -1, "FLUSHALL": -1,
+	"TOUCH": -2, "UNLINK": -2, "HSCAN": -3, "SSCAN": -3, "ZSCAN": -3,
+	"EXPIRE": -3, "PEXPIRE": -3, "EXPIREAT": -3, "PEXPIREAT": -3,
+	"TTL": 2, "PTTL": 2, "EXPIRETIME": 2, "PEXPIRETIME": 2, "PERSIST": 2,
+
+	// Lists
+	"LPUSH": -3, "RPUSH": -3, "LPUSHX": -3, "RPUSHX": -3, "LPOP": -2, "RPOP": -2,
+	"LLEN": 2, "LRANGE": 4, "LINDEX": 3, "LSET": 4, "LINSERT": 5, "LREM": 4,
+	"LTRIM": 4, "LPOS": -3, "BLPOP": -3, "BRPOP": -3, "LMOVE": 5, "BLMOVE": 6,
+	"RPOPLPUSH": 3, "BRPOPLPUSH": 4, "LMPOP": -4, "BLMPOP": -5,
+
+	// Sets
+	"SADD": -3, "SREM": -3, "SMEMBERS": 2, "SISMEMBER": 3, "SMISMEMBER": -3,
+	"SCARD": 2, "SPOP": -2, "SRANDMEMBER": -2, "SMOVE": 4,
+	"SINTER": -2, "SUNION": -2, "SDIFF": -2,
+	"SINTERSTORE": -3, "SUNIONSTORE": -3, "SDIFFSTORE": -3,
+
+	// Sorted sets
+	"ZADD": -4, "ZINCRBY": 4, "ZREM": -3, "ZSCORE": 3, "ZCARD": 2,
+	"ZRANK": -3, "ZREVRANK": -3, "ZRANGE": -4, "ZRANGESTORE": -5, "ZCOUNT": 4,
+	"ZPOPMIN": -2, "ZPOPMAX": -2,
+
+	// Server
+	"INFO": -1, "SAVE": 1, "BGSAVE": -1, "LASTSAVE": 1, "BGREWRITEAOF": 1,
+}
+
+// CheckArity reports an error if args is not a known command with an
+// acceptable number of arguments.
+func CheckArity(args []string) error {
+	name := strings.ToUpper(args[0])
+	arity, ok := arities[name]
+	if !ok {
+		return fmt.Errorf("unknown command '%s'", name)
+	}
+	if (arity > 0 && len(args) != arity) || (arity < 0 && len(args) < -arity) {
+		return fmt.Errorf("wrong no. of arguments for '%s'", strings.ToLower(name))
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"go_redis/internals/resp"
+	"go_redis/internals/store"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleExpire implements EXPIRE, PEXPIRE, EXPIREAT and PEXPIREAT, option
+// being the matching SET option for the time argument.
+func handleExpire(args []string, s *store.Store, w *resp.Writer, option string) {
+	name := strings.ToLower(args[0])
+	if len(args) < 3 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", name))
+		return
+	}
+	at, err := parseExpireAt(option, args[2], name)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+
+	var cond store.ExpireCondition
+	for _, arg := range args[3:] {
+		c, ok := parseExpireCondition(arg)
+		if !ok {
+			writeError(w, fmt.Sprintf("Unsupported option %s", arg))
+			return
+		}
+		cond |= c
+	}
+	if err := checkExpireCondition(cond); err != nil {
+		writeError(w, err.Error())
+		return
+	}
+
+	ok := s.ExpireAt(args[1], at, cond)
+	propagateAs(append([]string{"PEXPIREAT", args[1], strconv.FormatInt(at.UnixMilli(), 10)}, args[3:]...)...)
+	writeBool(w, ok)
+}
+
+// handleTTL implements TTL and PTTL, replying with the remaining time to
+// live of the key in units of unit.
+func handleTTL(args []string, s *store.Store, w *resp.Writer, unit time.Duration) {
+	if len(args) != 2 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+	at, ok := s.ExpireTime(args[1])
+	switch {
+	case !ok:
+		w.WriteInteger(-2)
+	case at.IsZero():
+		w.WriteInteger(-1)
+	default:
+		ms := max(time.Until(at).Milliseconds(), 0)
+		if unit == time.Second {
+			ms = (ms + 500) / 1000
+		}
+		w.WriteInteger(int(ms))
+	}
+}
+
+// handleExpireTime implements EXPIRETIME and PEXPIRETIME, replying with the
+// absolute unix time at which the key expires in units of unit.
+func handleExpireTime(args []string, s *store.Store, w *resp.Writer, unit time.Duration) {
+	if len(args) != 2 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+	at, ok := s.ExpireTime(args[1])
+	switch {
+	case !ok:
+		w.WriteInteger(-2)
+	case at.IsZero():
+		w.WriteInteger(-1)
+	case unit == time.Second:
+		w.WriteInteger(int(at.Unix()))
+	default:
+		w.WriteInteger(int(at.UnixMilli()))
+	}
+}
+
+func handlePersist(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'persist'")
+		return
+	}
+	writeBool(w, s.Persist(args[1]))
+}
+
+// parseExpireAt is parseExpireTime for the EXPIRE family, which also accepts
+// times that aren't positive, meaning the expiry has already passed.
+func parseExpireAt(option, arg, command string) (time.Time, error) {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return time.Time{}, errNotInteger
+	}
+	if n <= 0 {
+		// Seconds still have to fit in milliseconds, as in Redis.
+		if (option == "EX" || option == "EXAT") && n < math.MinInt64/1000 {
+			return time.Time{}, fmt.Errorf("invalid expire time in '%s' command", command)
+		}
+		return time.UnixMilli(0), nil
+	}
+	return parseExpireTime(option, arg, command)
+}
+
+func parseExpireCondition(arg string) (store.ExpireCondition, bool) {
+	switch strings.ToUpper(arg) {
+	case "NX":
+		return store.ExpireNX, true
+	case "XX":
+		return store.ExpireXX, true
+	case "GT":
+		return store.ExpireGT, true
+	case "LT":
+		return store.ExpireLT, true
+	}
+	return store.ExpireAlways, false
+}
+
+// checkExpireCondition rejects combinations of NX, XX, GT and LT that can
+// never be satisfied together.
+func checkExpireCondition(cond store.ExpireCondition) error {
+	if cond&store.ExpireNX != 0 && cond != store.ExpireNX {
+		return errors.New("NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if cond&store.ExpireGT != 0 && cond&store.ExpireLT != 0 {
+		return errors.New("GT and LT options at the same time are not compatible")
+	}
+	return nil
+}
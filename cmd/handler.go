@@ -2,310 +2,452 @@ package cmd
 
 import (
 	"fmt"
+	"go_redis/internals/resp"
 	"go_redis/internals/store"
-	"net"
-	"strconv"
 	"strings"
 	"time"
 )
 
-func Execute(args []string, s *store.Store, conn net.Conn) {
+// Execute runs a single command against database *db of dbs, which SELECT
+// changes. A blocking command that has nothing to serve returns a Block
+// instead of replying.
+func Execute(args []string, dbs store.Databases, db *int, w *resp.Writer) *Block {
 	fmt.Printf("ARGS: %#v\n", args)
 
 	if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
-		writeError(conn, "missing command")
-		return
+		writeError(w, "missing command")
+		return nil
 	}
 
 	cmd := strings.ToUpper(args[0])
+	s := dbs[*db]
+
+	dirty := dbs.Dirty()
+	rewrites = nil
+	defer func() {
+		if dbs.Dirty() != dirty {
+			propagate(args, dbs, *db)
+		}
+	}()
 
+	var block *Block
 	switch cmd {
 	case "PING":
-		handlePing(args, conn)
+		handlePing(args, w)
 
 	case "SET":
-		handleSet(args, s, conn)
+		handleSet(args, s, w)
 
 	case "GET":
-		handleGet(args, s, conn)
+		handleGet(args, s, w)
+
+	case "SETNX":
+		handleSetNX(args, s, w)
+
+	case "SETEX":
+		handleSetEx(args, s, w, "EX")
+
+	case "PSETEX":
+		handleSetEx(args, s, w, "PX")
+
+	case "GETSET":
+		handleGetSet(args, s, w)
+
+	case "GETDEL":
+		handleGetDel(args, s, w)
+
+	case "GETEX":
+		handleGetEx(args, s, w)
 
 	case "MSET":
-		handleMSet(args, s, conn)
+		handleMSet(args, s, w)
+
+	case "MSETNX":
+		handleMSetNX(args, s, w)
 
 	case "MGET":
-		handleMGet(args, s, conn)
+		handleMGet(args, s, w)
+
+	case "INCR":
+		handleIncr(args, s, w, 1, false)
+
+	case "DECR":
+		handleIncr(args, s, w, -1, false)
+
+	case "INCRBY":
+		handleIncr(args, s, w, 1, true)
+
+	case "DECRBY":
+		handleIncr(args, s, w, -1, true)
+
+	case "INCRBYFLOAT":
+		handleIncrByFloat(args, s, w)
+
+	case "APPEND":
+		handleAppend(args, s, w)
+
+	case "STRLEN":
+		handleStrLen(args, s, w)
+
+	case "GETRANGE":
+		handleGetRange(args, s, w)
+
+	case "SETRANGE":
+		handleSetRange(args, s, w)
+
+	case "LCS":
+		handleLCS(args, s, w)
 
 	case "HSET":
-		handleHSet(args, s, conn)
+		handleHSet(args, s, w)
 
 	case "HGET":
-		handleHGet(args, s, conn)
+		handleHGet(args, s, w)
 
 	case "HGETALL":
-		handleHGetAll(args, s, conn)
+		handleHGetAll(args, s, w)
+
+	case "HSETNX":
+		handleHSetNX(args, s, w)
+
+	case "HMGET":
+		handleHMGet(args, s, w)
+
+	case "HDEL":
+		handleHDel(args, s, w)
+
+	case "HEXISTS":
+		handleHExists(args, s, w)
+
+	case "HLEN":
+		handleHLen(args, s, w)
+
+	case "HSTRLEN":
+		handleHStrLen(args, s, w)
+
+	case "HKEYS":
+		handleHKeys(args, s, w)
+
+	case "HVALS":
+		handleHVals(args, s, w)
+
+	case "HINCRBY":
+		handleHIncrBy(args, s, w)
+
+	case "HINCRBYFLOAT":
+		handleHIncrByFloat(args, s, w)
+
+	case "HRANDFIELD":
+		handleHRandField(args, s, w)
+
+	case "HEXPIRE":
+		handleHExpire(args, s, w, "EX")
+
+	case "HPEXPIRE":
+		handleHExpire(args, s, w, "PX")
+
+	case "HEXPIREAT":
+		handleHExpire(args, s, w, "EXAT")
+
+	case "HPEXPIREAT":
+		handleHExpire(args, s, w, "PXAT")
+
+	case "HTTL":
+		handleHTTL(args, s, w, time.Second)
+
+	case "HPTTL":
+		handleHTTL(args, s, w, time.Millisecond)
+
+	case "HPERSIST":
+		handleHPersist(args, s, w)
 
 	case "DEL":
-		handleDel(args, s, conn)
+		handleDel(args, s, w)
 
 	case "EXISTS":
-		handleExists(args, s, conn)
+		handleExists(args, s, w)
+
+	case "TYPE":
+		handleType(args, s, w)
+
+	case "KEYS":
+		handleKeys(args, s, w)
+
+	case "SCAN":
+		handleScan(args, s, w)
+
+	case "RENAME":
+		handleRename(args, s, w, false)
+
+	case "RENAMENX":
+		handleRename(args, s, w, true)
+
+	case "COPY":
+		handleCopy(args, dbs, *db, w)
+
+	case "MOVE":
+		handleMove(args, dbs, *db, w)
+
+	case "SELECT":
+		handleSelect(args, dbs, db, w)
+
+	case "SWAPDB":
+		handleSwapDB(args, dbs, w)
+
+	case "RANDOMKEY":
+		handleRandomKey(args, s, w)
+
+	case "DBSIZE":
+		handleDBSize(args, s, w)
+
+	case "FLUSHDB":
+		handleFlush(args, dbs[*db:*db+1], w)
+
+	case "FLUSHALL":
+		handleFlush(args, dbs, w)
+
+	case "TOUCH":
+		handleTouch(args, s, w)
+
+	case "UNLINK":
+		handleUnlink(args, s, w)
+
+	case "HSCAN":
+		handleHScan(args, s, w)
+
+	case "SSCAN":
+		handleSScan(args, s, w)
+
+	case "ZSCAN":
+		handleZScan(args, s, w)
 
 	case "EXPIRE":
-		handleExpire(args, s, conn)
+		handleExpire(args, s, w, "EX")
+
+	case "PEXPIRE":
+		handleExpire(args, s, w, "PX")
+
+	case "EXPIREAT":
+		handleExpire(args, s, w, "EXAT")
+
+	case "PEXPIREAT":
+		handleExpire(args, s, w, "PXAT")
+
+	case "TTL":
+		handleTTL(args, s, w, time.Second)
+
+	case "PTTL":
+		handleTTL(args, s, w, time.Millisecond)
+
+	case "EXPIRETIME":
+		handleExpireTime(args, s, w, time.Second)
+
+	case "PEXPIRETIME":
+		handleExpireTime(args, s, w, time.Millisecond)
+
+	case "PERSIST":
+		handlePersist(args, s, w)
 
 	case "LPUSH":
-		handleLPush(args, s, conn)
+		handleLPush(args, s, w)
 
 	case "RPUSH":
-		handleRPush(args, s, conn)
+		handleRPush(args, s, w)
+
+	case "LPUSHX":
+		handlePushX(args, s, w, true)
+
+	case "RPUSHX":
+		handlePushX(args, s, w, false)
 
 	case "LPOP":
-		handleLPop(args, s, conn)
+		handlePop(args, s, w, true)
 
 	case "RPOP":
-		handleRPop(args, s, conn)
+		handlePop(args, s, w, false)
+
+	case "LLEN":
+		handleLLen(args, s, w)
+
+	case "LRANGE":
+		handleLRange(args, s, w)
+
+	case "LINDEX":
+		handleLIndex(args, s, w)
+
+	case "LSET":
+		handleLSet(args, s, w)
+
+	case "LINSERT":
+		handleLInsert(args, s, w)
+
+	case "LREM":
+		handleLRem(args, s, w)
+
+	case "LTRIM":
+		handleLTrim(args, s, w)
+
+	case "LPOS":
+		handleLPos(args, s, w)
 
 	case "BLPOP":
-		handleBLPop(args, s, conn)
+		block = handleBPop(args, s, w, true)
 
-	default:
-		fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", cmd)
-	}
-}
+	case "BRPOP":
+		block = handleBPop(args, s, w, false)
 
-func handlePing(args []string, conn net.Conn) {
-	if len(args) == 1 {
-		writeString(conn, "PONG")
-	} else {
-		writeBulkString(conn, args[1])
-	}
-}
+	case "LMOVE":
+		handleLMove(args, s, w)
 
-func handleSet(args []string, s *store.Store, conn net.Conn) {
-	if len(args) != 3 {
-		writeError(conn, "wrong no. of arguments for 'set'")
-		return
-	}
-	s.Set(args[1], args[2])
-	writeOk(conn)
-}
+	case "BLMOVE":
+		block = handleBLMove(args, s, w)
 
-func handleGet(args []string, s *store.Store, conn net.Conn) {
-	if len(args) != 2 {
-		writeError(conn, "wrong no. of arguments for 'get'")
-		return
-	}
-	if val, ok := s.Get(args[1]); ok {
-		writeBulkString(conn, val)
-	} else {
-		writeNullBulkString(conn)
-	}
-}
+	case "RPOPLPUSH":
+		handleRPopLPush(args, s, w)
 
-func handleMSet(args []string, s *store.Store, conn net.Conn) {
-	if len(args)%2 != 1 {
-		writeError(conn, "wrong no. of arguments for 'mset'")
-		return
-	}
-	for i := 1; i < len(args); i += 2 {
-		key := args[i]
-		val := args[i+1]
-		s.Set(key, val)
-	}
-	writeOk(conn)
-}
+	case "BRPOPLPUSH":
+		block = handleBRPopLPush(args, s, w)
 
-func handleMGet(args []string, s *store.Store, conn net.Conn) {
-	if len(args) < 2 {
-		writeError(conn, "wrong no. of arguments for 'mget'")
-		return
-	}
-	fmt.Fprintf(conn, "*%d\r\n", len(args)-1)
-	for _, key := range args[1:] {
-		if val, ok := s.Get(key); ok {
-			writeBulkString(conn, val)
-		} else {
-			writeNullBulkString(conn)
-		}
-	}
-}
+	case "LMPOP":
+		handleLMPop(args, s, w)
 
-func handleHSet(args []string, s *store.Store, conn net.Conn) {
-	if len(args) < 4 || len(args)%2 != 0 {
-		writeError(conn, "wrong no. of arguments for 'hset'")
-		return
-	}
-	key := args[1]
-	fields := args[2:]
+	case "BLMPOP":
+		block = handleBLMPop(args, s, w)
 
-	fieldMap := make(map[string]string)
-	for i := 0; i < len(fields); i += 2 {
-		fieldMap[fields[i]] = fields[i+1]
-	}
-	s.HSet(key, fieldMap)
-	writeOk(conn)
-}
+	case "SADD":
+		handleSAdd(args, s, w)
 
-func handleHGet(args []string, s *store.Store, conn net.Conn) {
-	if len(args) != 3 {
-		writeError(conn, "wrong no. of arguments for 'hget'")
-		return
-	}
-	if val, ok := s.HGet(args[1], args[2]); ok {
-		writeBulkString(conn, val)
-	} else {
-		writeNullBulkString(conn)
-	}
-}
+	case "SREM":
+		handleSRem(args, s, w)
 
-func handleHGetAll(args []string, s *store.Store, conn net.Conn) {
-	if len(args) != 2 {
-		writeError(conn, "wrong no. of arguments for 'hgetall'")
-		return
-	}
-	all := s.HGetAll(args[1])
-	if all == nil {
-		writeNullBulkString(conn)
-		return
-	}
+	case "SMEMBERS":
+		handleSMembers(args, s, w)
 
-	fmt.Fprintf(conn, "*%d\r\n", len(all)*2)
-	for k, v := range all {
-		fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(k), k)
-		fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(v), v)
+	case "SISMEMBER":
+		handleSIsMember(args, s, w)
 
-	}
-}
+	case "SMISMEMBER":
+		handleSMIsMember(args, s, w)
 
-func handleDel(args []string, s *store.Store, conn net.Conn) {
-	if len(args) != 2 {
-		writeError(conn, "wrong no. of arguments for 'del'")
-		return
-	}
-	if deleted := s.Del(args[1]); deleted {
-		fmt.Fprint(conn, ":1\r\n")
-	} else {
-		fmt.Fprint(conn, ":0\r\n")
-	}
-}
+	case "SCARD":
+		handleSCard(args, s, w)
 
-func handleExists(args []string, s *store.Store, conn net.Conn) {
-	if len(args) != 2 {
-		writeError(conn, "wrong no. of arguments for 'exists'")
-		return
-	}
-	if s.Exists(args[1]) {
-		fmt.Fprint(conn, ":1\r\n")
-	} else {
-		fmt.Fprint(conn, ":0\r\n")
-	}
-}
+	case "SPOP":
+		handleSPop(args, s, w)
 
-func handleExpire(args []string, s *store.Store, conn net.Conn) {
-	if len(args) != 3 {
-		writeError(conn, "wrong no. of arguments for 'expire'")
-		return
-	}
-	seconds, err := strconv.Atoi(args[2])
-	if err != nil || seconds < 0 {
-		writeError(conn, "invalid seconds")
-		return
-	}
-	if ok := s.Expire(args[1], seconds); ok {
-		fmt.Fprint(conn, ":1\r\n")
-	} else {
-		fmt.Fprint(conn, ":0\r\n")
-	}
-}
+	case "SRANDMEMBER":
+		handleSRandMember(args, s, w)
 
-func handleLPush(args []string, s *store.Store, conn net.Conn) {
-	if len(args) < 3 {
-		writeError(conn, "wrong no. of arguments for 'lpush'")
-		return
-	}
-	key := args[1]
-	values := args[2:]
+	case "SMOVE":
+		handleSMove(args, s, w)
 
-	count := s.LPush(key, values...)
-	writeInteger(conn, count)
-}
+	case "SINTER":
+		handleSetAlgebra(args, w, s.SInter)
 
-func handleRPush(args []string, s *store.Store, conn net.Conn) {
-	if len(args) < 3 {
-		writeError(conn, "wrong no. of arguments for 'rpush'")
-		return
-	}
+	case "SUNION":
+		handleSetAlgebra(args, w, s.SUnion)
 
-	key := args[1]
-	values := args[2:]
+	case "SDIFF":
+		handleSetAlgebra(args, w, s.SDiff)
 
-	count := s.RPush(key, values...)
-	writeInteger(conn, count)
-}
+	case "SINTERSTORE":
+		handleSetAlgebraStore(args, w, s.SInterStore)
 
-func handleLPop(args []string, s *store.Store, conn net.Conn) {
-	if len(args) != 2 {
-		writeError(conn, "wrong no. of arguments for 'lpop'")
-		return
-	}
+	case "SUNIONSTORE":
+		handleSetAlgebraStore(args, w, s.SUnionStore)
 
-	key := args[1]
-	val, ok := s.LPop(key)
-	if !ok {
-		writeNullBulkString(conn)
-		return
+	case "SDIFFSTORE":
+		handleSetAlgebraStore(args, w, s.SDiffStore)
+
+	case "ZADD":
+		handleZAdd(args, s, w)
+
+	case "ZINCRBY":
+		handleZIncrBy(args, s, w)
+
+	case "ZREM":
+		handleZRem(args, s, w)
+
+	case "ZSCORE":
+		handleZScore(args, s, w)
+
+	case "ZCARD":
+		handleZCard(args, s, w)
+
+	case "ZRANK":
+		handleZRank(args, s, w, false)
+
+	case "ZREVRANK":
+		handleZRank(args, s, w, true)
+
+	case "ZRANGE":
+		handleZRange(args, s, w)
+
+	case "ZRANGESTORE":
+		handleZRangeStore(args, s, w)
+
+	case "ZCOUNT":
+		handleZCount(args, s, w)
+
+	case "ZPOPMIN":
+		handleZPop(args, s, w, false)
+
+	case "ZPOPMAX":
+		handleZPop(args, s, w, true)
+
+	case "INFO":
+		handleInfo(args, dbs, w)
+
+	case "SAVE":
+		handleSave(args, dbs, w)
+
+	case "BGSAVE":
+		handleBGSave(args, dbs, w)
+
+	case "LASTSAVE":
+		handleLastSave(args, w)
+
+	case "BGREWRITEAOF":
+		handleBGRewriteAOF(args, dbs, w)
+
+	default:
+		writeError(w, fmt.Sprintf("unknown command '%s'", cmd))
 	}
-	writeBulkString(conn, val)
+	return block
 }
 
-func handleRPop(args []string, s *store.Store, conn net.Conn) {
-	if len(args) != 2 {
-		writeError(conn, "wrong no. of arguments for 'rpop'")
-		return
+func handlePing(args []string, w *resp.Writer) {
+	if len(args) == 1 {
+		w.WriteSimpleString("PONG")
+	} else {
+		w.WriteBulkString(args[1])
 	}
+}
 
-	key := args[1]
-	val, ok := s.RPop(key)
-	if !ok {
-		writeNullBulkString(conn)
+func handleDel(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'del'")
 		return
 	}
-	writeBulkString(conn, val)
+	writeBool(w, s.Del(args[1]))
 }
 
-func handleBLPop(args []string, s *store.Store, conn net.Conn) {
-	if len(args) < 2 {
-		writeError(conn, "wrong no. of arguments for 'blpop'")
+func handleExists(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'exists'")
 		return
 	}
+	writeBool(w, s.Exists(args[1]))
+}
 
-	keys := args[:len(args)-1]
-	timeout, err := strconv.Atoi(args[len(args)-1])
-
-	if err != nil {
-		writeError(conn, "timeout is not an integer")
+func handleType(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'type'")
 		return
 	}
-
-	waitCh := make(chan [2]string, 1)
-
-	for _, key := range keys {
-		val, ok := s.LPop(key)
-		if ok {
-			writeArray(conn, []string{key, val})
-			return
-		}
-	}
-
-	for _, key := range keys {
-		s.RegisterWaiter(key, waitCh)
-	}
-
-	select {
-	case result := <-waitCh:
-		writeArray(conn, []string{result[0], result[1]})
-
-	case <-time.After(time.Duration(timeout) * time.Second):
-		writeNullArray(conn)
+	if typ, ok := s.Type(args[1]); ok {
+		w.WriteSimpleString(typ.String())
+	} else {
+		w.WriteSimpleString("none")
 	}
 }
@@ -0,0 +1,440 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"go_redis/internals/resp"
+	"go_redis/internals/store"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errFieldsMissing     = errors.New("Mandatory argument FIELDS is missing or not at the right position")
+	errNumFields         = errors.New("Parameter `numFields` should be greater than 0")
+	errNumFieldsMismatch = errors.New("The `numfields` parameter must match the number of arguments")
+	errValueRange        = errors.New("value is out of range")
+)
+
+// maxRandomCount bounds how many elements a negative HRANDFIELD or
+// SRANDMEMBER count may ask for. Those repeat elements, so without a bound
+// one request could make the server build an arbitrarily large reply.
+const maxRandomCount = 1 << 24
+
+// parseRandomCount parses the count of HRANDFIELD and SRANDMEMBER.
+func parseRandomCount(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, errNotInteger
+	}
+	if n < -maxRandomCount {
+		return 0, errValueRange
+	}
+	return n, nil
+}
+
+func handleHSet(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 4 || len(args)%2 != 0 {
+		writeError(w, "wrong no. of arguments for 'hset'")
+		return
+	}
+	key := args[1]
+	fields := args[2:]
+
+	fieldMap := make(map[string]string)
+	for i := 0; i < len(fields); i += 2 {
+		fieldMap[fields[i]] = fields[i+1]
+	}
+	added, err := s.HSet(key, fieldMap)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(added)
+}
+
+func handleHSetNX(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'hsetnx'")
+		return
+	}
+	ok, err := s.HSetNX(args[1], args[2], args[3])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeBool(w, ok)
+}
+
+func handleHGet(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'hget'")
+		return
+	}
+	val, ok, err := s.HGet(args[1], args[2])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if ok {
+		w.WriteBulkString(val)
+	} else {
+		w.WriteNull()
+	}
+}
+
+func handleHMGet(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'hmget'")
+		return
+	}
+	values, found, err := s.HMGet(args[1], args[2:]...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteArrayLen(len(values))
+	for i, val := range values {
+		if found[i] {
+			w.WriteBulkString(val)
+		} else {
+			w.WriteNull()
+		}
+	}
+}
+
+func handleHGetAll(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'hgetall'")
+		return
+	}
+	all, err := s.HGetAll(args[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteMapLen(len(all))
+	for k, v := range all {
+		w.WriteBulkString(k)
+		w.WriteBulkString(v)
+	}
+}
+
+func handleHDel(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'hdel'")
+		return
+	}
+	removed, err := s.HDel(args[1], args[2:]...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(removed)
+}
+
+func handleHExists(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'hexists'")
+		return
+	}
+	ok, err := s.HExists(args[1], args[2])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeBool(w, ok)
+}
+
+func handleHLen(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'hlen'")
+		return
+	}
+	n, err := s.HLen(args[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleHStrLen(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'hstrlen'")
+		return
+	}
+	n, err := s.HStrLen(args[1], args[2])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleHKeys(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'hkeys'")
+		return
+	}
+	keys, err := s.HKeys(args[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteBulkStrings(keys)
+}
+
+func handleHVals(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'hvals'")
+		return
+	}
+	vals, err := s.HVals(args[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteBulkStrings(vals)
+}
+
+func handleHIncrBy(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'hincrby'")
+		return
+	}
+	delta, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		writeError(w, errNotInteger.Error())
+		return
+	}
+	n, err := s.HIncrBy(args[1], args[2], delta)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(int(n))
+}
+
+func handleHIncrByFloat(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'hincrbyfloat'")
+		return
+	}
+	incr, err := strconv.ParseFloat(args[3], 64)
+	if err != nil || math.IsNaN(incr) || math.IsInf(incr, 0) {
+		writeError(w, errNotFloat.Error())
+		return
+	}
+	val, err := s.HIncrByFloat(args[1], args[2], incr)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	propagateAs("HSET", args[1], args[2], val)
+	// HSET clears the field's expiry, so log it again if there is one.
+	if exp, _ := s.HExpireTime(args[1], args[2]); len(exp) == 1 && exp[0] > 0 {
+		propagateAs("HPEXPIREAT", args[1], strconv.FormatInt(exp[0], 10), "FIELDS", "1", args[2])
+	}
+	w.WriteBulkString(val)
+}
+
+func handleHRandField(args []string, s *store.Store, w *resp.Writer) {
+	withValues := len(args) == 4 && strings.ToUpper(args[3]) == "WITHVALUES"
+	if len(args) != 2 && len(args) != 3 && !withValues {
+		if len(args) == 4 {
+			writeError(w, errSyntax.Error())
+		} else {
+			writeError(w, "wrong no. of arguments for 'hrandfield'")
+		}
+		return
+	}
+
+	count := 1
+	if len(args) > 2 {
+		n, err := parseRandomCount(args[2])
+		if err != nil {
+			writeError(w, err.Error())
+			return
+		}
+		count = n
+	}
+
+	fields, values, err := s.HRandField(args[1], count)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	switch {
+	case len(args) == 2 && len(fields) == 0:
+		w.WriteNull()
+	case len(args) == 2:
+		w.WriteBulkString(fields[0])
+	case withValues && w.Protocol() >= 3:
+		// RESP3 clients get each field and value as a pair.
+		w.WriteArrayLen(len(fields))
+		for i, f := range fields {
+			w.WriteBulkStrings([]string{f, values[i]})
+		}
+	case withValues:
+		w.WriteArrayLen(len(fields) * 2)
+		for i, f := range fields {
+			w.WriteBulkString(f)
+			w.WriteBulkString(values[i])
+		}
+	default:
+		w.WriteBulkStrings(fields)
+	}
+}
+
+// handleHExpire implements HEXPIRE, HPEXPIRE, HEXPIREAT and HPEXPIREAT,
+// option being the matching SET option for the time argument.
+func handleHExpire(args []string, s *store.Store, w *resp.Writer, option string) {
+	name := strings.ToLower(args[0])
+	if len(args) < 6 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", name))
+		return
+	}
+
+	at, err := parseExpireAt(option, args[2], name)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	if strings.HasPrefix(args[2], "-") {
+		writeError(w, "invalid expire time, must be >= 0")
+		return
+	}
+
+	rest := args[3:]
+	cond, ok := parseExpireCondition(rest[0])
+	if ok {
+		rest = rest[1:]
+	}
+	fields, err := parseFields(rest)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+
+	result, err := s.HExpireAt(args[1], at, cond, fields...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	propagateAs(append([]string{"HPEXPIREAT", args[1], strconv.FormatInt(at.UnixMilli(), 10)}, args[3:]...)...)
+	w.WriteArrayLen(len(result))
+	for _, r := range result {
+		w.WriteInteger(r)
+	}
+}
+
+// handleHTTL implements HTTL and HPTTL, replying with the remaining time to
+// live of each field in units of unit.
+func handleHTTL(args []string, s *store.Store, w *resp.Writer, unit time.Duration) {
+	if len(args) < 5 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+	fields, err := parseFields(args[2:])
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	expiries, err := s.HExpireTime(args[1], fields...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	w.WriteArrayLen(len(expiries))
+	for _, exp := range expiries {
+		if exp < 0 {
+			w.WriteInteger(int(exp))
+			continue
+		}
+		ms := max(exp-now, 0)
+		if unit == time.Second {
+			w.WriteInteger(int((ms + 500) / 1000))
+		} else {
+			w.WriteInteger(int(ms))
+		}
+	}
+}
+
+func handleHPersist(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 5 {
+		writeError(w, "wrong no. of arguments for 'hpersist'")
+		return
+	}
+	fields, err := parseFields(args[2:])
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	result, err := s.HPersist(args[1], fields...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteArrayLen(len(result))
+	for _, r := range result {
+		w.WriteInteger(r)
+	}
+}
+
+// parseFields parses "FIELDS numfields field [field ...]".
+func parseFields(args []string) ([]string, error) {
+	if len(args) < 2 || strings.ToUpper(args[0]) != "FIELDS" {
+		return nil, errFieldsMissing
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n <= 0 {
+		return nil, errNumFields
+	}
+	if n != len(args)-2 {
+		return nil, errNumFieldsMismatch
+	}
+	return args[2:], nil
+}
+
+func handleHScan(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'hscan'")
+		return
+	}
+	// NOVALUES may appear among the options, take it out before parsing.
+	scanArgs := args[2:]
+	noValues := false
+	for i := 1; i < len(scanArgs); i += 2 {
+		if strings.ToUpper(scanArgs[i]) == "NOVALUES" {
+			noValues = true
+			scanArgs = append(scanArgs[:i:i], scanArgs[i+1:]...)
+			break
+		}
+	}
+	cursor, opts, err := parseScan(scanArgs, false)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+
+	next, items, err := s.HScan(args[1], cursor, opts)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if noValues {
+		fields := make([]string, 0, len(items)/2)
+		for i := 0; i < len(items); i += 2 {
+			fields = append(fields, items[i])
+		}
+		items = fields
+	}
+	writeScanReply(w, next, items)
+}
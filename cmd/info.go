@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"go_redis/internals/resp"
+	"go_redis/internals/store"
+	"strings"
+)
+
+// infoSections are the sections INFO reports, in the order they are written
+// when all of them are asked for.
+var infoSections = []struct {
+	name  string
+	write func(b *strings.Builder, dbs store.Databases)
+}{
+	{"stats", writeStatsInfo},
+	{"keyspace", writeKeyspaceInfo},
+}
+
+func handleInfo(args []string, dbs store.Databases, w *resp.Writer) {
+	all := len(args) == 1
+	wanted := make(map[string]bool)
+	for _, arg := range args[1:] {
+		switch section := strings.ToLower(arg); section {
+		case "all", "default", "everything":
+			all = true
+		default:
+			wanted[section] = true
+		}
+	}
+
+	var b strings.Builder
+	for _, section := range infoSections {
+		if !all && !wanted[section.name] {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		section.write(&b, dbs)
+	}
+	w.WriteVerbatim("txt", b.String())
+}
+
+func writeStatsInfo(b *strings.Builder, dbs store.Databases) {
+	stats := dbs.ExpireStats()
+	b.WriteString("# Stats\r\n")
+	fmt.Fprintf(b, "expired_keys:%d\r\n", stats.ExpiredKeys)
+	fmt.Fprintf(b, "expired_subkeys:%d\r\n", stats.ExpiredFields)
+	fmt.Fprintf(b, "expired_stale_perc:%.2f\r\n", stats.StalePercent)
+	fmt.Fprintf(b, "expired_time_cap_reached_count:%d\r\n", stats.TimeCapReached)
+	fmt.Fprintf(b, "expire_cycle_cpu_milliseconds:%d\r\n", stats.CycleTime.Milliseconds())
+}
+
+// writeKeyspaceInfo lists every database that holds keys.
+func writeKeyspaceInfo(b *strings.Builder, dbs store.Databases) {
+	b.WriteString("# Keyspace\r\n")
+	for i, s := range dbs {
+		if keys := s.DBSize(); keys > 0 {
+			fmt.Fprintf(b, "db%d:keys=%d,expires=%d\r\n", i, keys, s.Expires())
+		}
+	}
+}
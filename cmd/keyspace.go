@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"go_redis/internals/resp"
+	"go_redis/internals/store"
+	"strconv"
+	"strings"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// defaultScanCount is how much work a scan call does when COUNT is not given.
+const defaultScanCount = 10
+
+func handleKeys(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'keys'")
+		return
+	}
+	w.WriteBulkStrings(s.Keys(args[1]))
+}
+
+func handleScan(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 2 {
+		writeError(w, "wrong no. of arguments for 'scan'")
+		return
+	}
+	cursor, opts, err := parseScan(args[1:], true)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	next, keys := s.Scan(cursor, opts)
+	writeScanReply(w, next, keys)
+}
+
+// parseScan parses "cursor [MATCH pattern] [COUNT count] [TYPE type]" as
+// accepted by SCAN, and by HSCAN, SSCAN and ZSCAN without TYPE.
+func parseScan(args []string, allowType bool) (uint64, store.ScanOptions, error) {
+	opts := store.ScanOptions{Count: defaultScanCount}
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return 0, opts, errInvalidCursor
+	}
+
+	for i := 1; i < len(args); i += 2 {
+		option := strings.ToUpper(args[i])
+		if i+1 >= len(args) {
+			return 0, opts, errSyntax
+		}
+		switch arg := args[i+1]; {
+		case option == "MATCH":
+			opts.Match = arg
+			if arg == "*" {
+				opts.Match = ""
+			}
+		case option == "COUNT":
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				return 0, opts, errNotInteger
+			}
+			if n < 1 {
+				return 0, opts, errSyntax
+			}
+			opts.Count = n
+		case option == "TYPE" && allowType:
+			if !validTypeName(arg) {
+				return 0, opts, fmt.Errorf("unknown type name '%s'", arg)
+			}
+			opts.Type = strings.ToLower(arg)
+		default:
+			return 0, opts, errSyntax
+		}
+	}
+	return cursor, opts, nil
+}
+
+func validTypeName(name string) bool {
+	switch strings.ToLower(name) {
+	case "string", "list", "hash", "set", "zset":
+		return true
+	}
+	return false
+}
+
+func writeScanReply(w *resp.Writer, cursor uint64, items []string) {
+	w.WriteArrayLen(2)
+	w.WriteBulkString(strconv.FormatUint(cursor, 10))
+	w.WriteBulkStrings(items)
+}
+
+var (
+	errSameObject   = errors.New("source and destination objects are the same")
+	errDBIndexRange = errors.New("DB index is out of range")
+)
+
+func handleRename(args []string, s *store.Store, w *resp.Writer, nx bool) {
+	if len(args) != 3 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+	renamed, err := s.Rename(args[1], args[2], nx)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if nx {
+		writeBool(w, renamed)
+		return
+	}
+	writeOk(w)
+}
+
+func handleCopy(args []string, dbs store.Databases, db int, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'copy'")
+		return
+	}
+	to := db
+	replace := false
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "REPLACE":
+			replace = true
+		case "DB":
+			if i+1 >= len(args) {
+				writeError(w, errSyntax.Error())
+				return
+			}
+			i++
+			n, err := parseDBIndex(args[i], dbs)
+			if err != nil {
+				writeError(w, err.Error())
+				return
+			}
+			to = n
+		default:
+			writeError(w, errSyntax.Error())
+			return
+		}
+	}
+	if to == db && args[1] == args[2] {
+		writeError(w, errSameObject.Error())
+		return
+	}
+	writeBool(w, dbs[db].Copy(args[1], dbs[to], args[2], replace))
+}
+
+func handleMove(args []string, dbs store.Databases, db int, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'move'")
+		return
+	}
+	to, err := parseDBIndex(args[2], dbs)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	if to == db {
+		writeError(w, errSameObject.Error())
+		return
+	}
+	writeBool(w, dbs[db].Move(args[1], dbs[to]))
+}
+
+func handleSelect(args []string, dbs store.Databases, db *int, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'select'")
+		return
+	}
+	n, err := parseDBIndex(args[1], dbs)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	*db = n
+	writeOk(w)
+}
+
+func handleSwapDB(args []string, dbs store.Databases, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'swapdb'")
+		return
+	}
+	a, err := strconv.Atoi(args[1])
+	if err != nil {
+		writeError(w, "invalid first DB index")
+		return
+	}
+	b, err := strconv.Atoi(args[2])
+	if err != nil {
+		writeError(w, "invalid second DB index")
+		return
+	}
+	if a < 0 || a >= len(dbs) || b < 0 || b >= len(dbs) {
+		writeError(w, errDBIndexRange.Error())
+		return
+	}
+	dbs.Swap(a, b)
+	writeOk(w)
+}
+
+// parseDBIndex parses the index of one of dbs.
+func parseDBIndex(arg string, dbs store.Databases) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, errNotInteger
+	}
+	if n < 0 || n >= len(dbs) {
+		return 0, errDBIndexRange
+	}
+	return n, nil
+}
+
+func handleRandomKey(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 1 {
+		writeError(w, "wrong no. of arguments for 'randomkey'")
+		return
+	}
+	if key, ok := s.RandomKey(); ok {
+		w.WriteBulkString(key)
+	} else {
+		w.WriteNull()
+	}
+}
+
+func handleDBSize(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 1 {
+		writeError(w, "wrong no. of arguments for 'dbsize'")
+		return
+	}
+	w.WriteInteger(s.DBSize())
+}
+
+// handleFlush runs FLUSHDB and FLUSHALL, which flush the selected database
+// and every database respectively.
+func handleFlush(args []string, dbs store.Databases, w *resp.Writer) {
+	if len(args) > 2 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+	async := false
+	if len(args) == 2 {
+		switch strings.ToUpper(args[1]) {
+		case "ASYNC":
+			async = true
+		case "SYNC":
+		default:
+			writeError(w, errSyntax.Error())
+			return
+		}
+	}
+	for _, s := range dbs {
+		s.Flush(async)
+	}
+	writeOk(w)
+}
+
+func handleTouch(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 2 {
+		writeError(w, "wrong no. of arguments for 'touch'")
+		return
+	}
+	w.WriteInteger(s.Touch(args[1:]...))
+}
+
+func handleUnlink(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 2 {
+		writeError(w, "wrong no. of arguments for 'unlink'")
+		return
+	}
+	w.WriteInteger(s.Unlink(args[1:]...))
+}
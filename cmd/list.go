@@ -0,0 +1,527 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"go_redis/internals/resp"
+	"go_redis/internals/store"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func handleLPush(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'lpush'")
+		return
+	}
+	key := args[1]
+	values := args[2:]
+
+	count, err := s.LPush(key, values...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(count)
+}
+
+func handleRPush(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'rpush'")
+		return
+	}
+
+	key := args[1]
+	values := args[2:]
+
+	count, err := s.RPush(key, values...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(count)
+}
+
+func handlePushX(args []string, s *store.Store, w *resp.Writer, left bool) {
+	if len(args) < 3 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+	push := s.RPushX
+	if left {
+		push = s.LPushX
+	}
+	count, err := push(args[1], args[2:]...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(count)
+}
+
+// handlePop implements LPOP and RPOP. Without a count the reply is a single
+// element, with one it is an array of up to count elements.
+func handlePop(args []string, s *store.Store, w *resp.Writer, left bool) {
+	if len(args) != 2 && len(args) != 3 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+
+	key := args[1]
+	if len(args) == 3 {
+		count, err := strconv.Atoi(args[2])
+		if err != nil || count < 0 {
+			writeError(w, "value is out of range, must be positive")
+			return
+		}
+		pop := s.RPopCount
+		if left {
+			pop = s.LPopCount
+		}
+		popped, err := pop(key, count)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if popped == nil {
+			w.WriteNullArray()
+			return
+		}
+		w.WriteBulkStrings(popped)
+		return
+	}
+
+	pop := s.RPop
+	if left {
+		pop = s.LPop
+	}
+	val, ok, err := pop(key)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !ok {
+		w.WriteNull()
+		return
+	}
+	w.WriteBulkString(val)
+}
+
+func handleLLen(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'llen'")
+		return
+	}
+	n, err := s.LLen(args[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleLRange(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'lrange'")
+		return
+	}
+	start, err1 := strconv.Atoi(args[2])
+	stop, err2 := strconv.Atoi(args[3])
+	if err1 != nil || err2 != nil {
+		writeError(w, errNotInteger.Error())
+		return
+	}
+	values, err := s.LRange(args[1], start, stop)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteBulkStrings(values)
+}
+
+func handleLIndex(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'lindex'")
+		return
+	}
+	index, err := strconv.Atoi(args[2])
+	if err != nil {
+		writeError(w, errNotInteger.Error())
+		return
+	}
+	val, ok, err := s.LIndex(args[1], index)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !ok {
+		w.WriteNull()
+		return
+	}
+	w.WriteBulkString(val)
+}
+
+func handleLSet(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'lset'")
+		return
+	}
+	index, err := strconv.Atoi(args[2])
+	if err != nil {
+		writeError(w, errNotInteger.Error())
+		return
+	}
+	if err := s.LSet(args[1], index, args[3]); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeOk(w)
+}
+
+func handleLInsert(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 5 {
+		writeError(w, "wrong no. of arguments for 'linsert'")
+		return
+	}
+	var before bool
+	switch strings.ToUpper(args[2]) {
+	case "BEFORE":
+		before = true
+	case "AFTER":
+	default:
+		writeError(w, errSyntax.Error())
+		return
+	}
+	n, err := s.LInsert(args[1], before, args[3], args[4])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleLRem(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'lrem'")
+		return
+	}
+	count, err := strconv.Atoi(args[2])
+	if err != nil {
+		writeError(w, errNotInteger.Error())
+		return
+	}
+	removed, err := s.LRem(args[1], count, args[3])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(removed)
+}
+
+func handleLTrim(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'ltrim'")
+		return
+	}
+	start, err1 := strconv.Atoi(args[2])
+	stop, err2 := strconv.Atoi(args[3])
+	if err1 != nil || err2 != nil {
+		writeError(w, errNotInteger.Error())
+		return
+	}
+	if err := s.LTrim(args[1], start, stop); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeOk(w)
+}
+
+func handleLPos(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 || len(args)%2 == 0 {
+		writeError(w, "wrong no. of arguments for 'lpos'")
+		return
+	}
+
+	opts := store.LPosOptions{Rank: 1}
+	withCount := false
+	for i := 3; i < len(args); i += 2 {
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			writeError(w, errNotInteger.Error())
+			return
+		}
+		switch strings.ToUpper(args[i]) {
+		case "RANK":
+			if n == 0 {
+				writeError(w, "RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+				return
+			}
+			// The rank is negated to count from the end, which MinInt
+			// can't be.
+			if n == math.MinInt {
+				writeError(w, "value is out of range, value must between -9223372036854775807 and 9223372036854775807")
+				return
+			}
+			opts.Rank = n
+		case "COUNT":
+			if n < 0 {
+				writeError(w, "COUNT can't be negative")
+				return
+			}
+			opts.Count = n
+			withCount = true
+		case "MAXLEN":
+			if n < 0 {
+				writeError(w, "MAXLEN can't be negative")
+				return
+			}
+			opts.MaxLen = n
+		default:
+			writeError(w, errSyntax.Error())
+			return
+		}
+	}
+	if !withCount {
+		opts.Count = 1
+	}
+
+	positions, err := s.LPos(args[1], args[2], opts)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if withCount {
+		w.WriteArrayLen(len(positions))
+		for _, p := range positions {
+			w.WriteInteger(p)
+		}
+		return
+	}
+	if len(positions) == 0 {
+		w.WriteNull()
+		return
+	}
+	w.WriteInteger(positions[0])
+}
+
+// handleBPop implements BLPOP and BRPOP, popping from the first non-empty
+// list among the keys.
+func handleBPop(args []string, s *store.Store, w *resp.Writer, left bool) *Block {
+	if len(args) < 3 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return nil
+	}
+
+	keys := args[1 : len(args)-1]
+	timeout, err := parseTimeout(args[len(args)-1])
+	if err != nil {
+		writeError(w, err.Error())
+		return nil
+	}
+
+	pop, popName := s.RPop, "RPOP"
+	if left {
+		pop, popName = s.LPop, "LPOP"
+	}
+	for _, key := range keys {
+		val, ok, err := pop(key)
+		if err != nil {
+			writeStoreError(w, err)
+			return nil
+		}
+		if ok {
+			propagateAs(popName, key)
+			w.WriteBulkStrings([]string{key, val})
+			return nil
+		}
+	}
+
+	return &Block{Keys: keys, Timeout: timeout, timeout: (*resp.Writer).WriteNullArray}
+}
+
+func handleLMove(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 5 {
+		writeError(w, "wrong no. of arguments for 'lmove'")
+		return
+	}
+	fromLeft, err1 := parseListEnd(args[3])
+	toLeft, err2 := parseListEnd(args[4])
+	if err1 != nil || err2 != nil {
+		writeError(w, errSyntax.Error())
+		return
+	}
+	move(args[1], args[2], fromLeft, toLeft, s, w)
+}
+
+func handleRPopLPush(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'rpoplpush'")
+		return
+	}
+	move(args[1], args[2], false, true, s, w)
+}
+
+func handleBLMove(args []string, s *store.Store, w *resp.Writer) *Block {
+	if len(args) != 6 {
+		writeError(w, "wrong no. of arguments for 'blmove'")
+		return nil
+	}
+	fromLeft, err1 := parseListEnd(args[3])
+	toLeft, err2 := parseListEnd(args[4])
+	if err1 != nil || err2 != nil {
+		writeError(w, errSyntax.Error())
+		return nil
+	}
+	timeout, err := parseTimeout(args[5])
+	if err != nil {
+		writeError(w, err.Error())
+		return nil
+	}
+	return blockingMove(args[1], args[2], fromLeft, toLeft, timeout, s, w)
+}
+
+func handleBRPopLPush(args []string, s *store.Store, w *resp.Writer) *Block {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'brpoplpush'")
+		return nil
+	}
+	timeout, err := parseTimeout(args[3])
+	if err != nil {
+		writeError(w, err.Error())
+		return nil
+	}
+	return blockingMove(args[1], args[2], false, true, timeout, s, w)
+}
+
+func move(src, dst string, fromLeft, toLeft bool, s *store.Store, w *resp.Writer) {
+	val, ok, err := s.LMove(src, dst, fromLeft, toLeft)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !ok {
+		w.WriteNull()
+		return
+	}
+	w.WriteBulkString(val)
+}
+
+func blockingMove(src, dst string, fromLeft, toLeft bool, timeout time.Duration, s *store.Store, w *resp.Writer) *Block {
+	val, ok, err := s.LMove(src, dst, fromLeft, toLeft)
+	if err != nil {
+		writeStoreError(w, err)
+		return nil
+	}
+	if !ok {
+		return &Block{Keys: []string{src}, Timeout: timeout, timeout: (*resp.Writer).WriteNull}
+	}
+	propagateAs("LMOVE", src, dst, listEnd(fromLeft), listEnd(toLeft))
+	w.WriteBulkString(val)
+	return nil
+}
+
+func handleLMPop(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 4 {
+		writeError(w, "wrong no. of arguments for 'lmpop'")
+		return
+	}
+	keys, left, count, err := parseLMPop(args[1:])
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	key, popped, err := s.LMPop(keys, count, left)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if popped == nil {
+		w.WriteNullArray()
+		return
+	}
+	writeListPop(w, key, popped)
+}
+
+func handleBLMPop(args []string, s *store.Store, w *resp.Writer) *Block {
+	if len(args) < 5 {
+		writeError(w, "wrong no. of arguments for 'blmpop'")
+		return nil
+	}
+	timeout, err := parseTimeout(args[1])
+	if err != nil {
+		writeError(w, err.Error())
+		return nil
+	}
+	keys, left, count, err := parseLMPop(args[2:])
+	if err != nil {
+		writeError(w, err.Error())
+		return nil
+	}
+	key, popped, err := s.LMPop(keys, count, left)
+	if err != nil {
+		writeStoreError(w, err)
+		return nil
+	}
+	if popped == nil {
+		return &Block{Keys: keys, Timeout: timeout, timeout: (*resp.Writer).WriteNullArray}
+	}
+	propagateAs("LMPOP", "1", key, listEnd(left), "COUNT", strconv.Itoa(count))
+	writeListPop(w, key, popped)
+	return nil
+}
+
+// parseLMPop parses "numkeys key [key ...] LEFT|RIGHT [COUNT count]".
+func parseLMPop(args []string) (keys []string, left bool, count int, err error) {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil || numKeys <= 0 {
+		return nil, false, 0, errors.New("numkeys should be greater than 0")
+	}
+	if numKeys > len(args)-2 {
+		return nil, false, 0, errSyntax
+	}
+	keys = args[1 : numKeys+1]
+	left, err = parseListEnd(args[numKeys+1])
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	count = 1
+	rest := args[numKeys+2:]
+	switch {
+	case len(rest) == 0:
+	case len(rest) == 2 && strings.ToUpper(rest[0]) == "COUNT":
+		count, err = strconv.Atoi(rest[1])
+		if err != nil || count <= 0 {
+			return nil, false, 0, errors.New("count should be greater than 0")
+		}
+	default:
+		return nil, false, 0, errSyntax
+	}
+	return keys, left, count, nil
+}
+
+// parseListEnd parses LEFT or RIGHT, reporting whether it was LEFT.
+func parseListEnd(s string) (bool, error) {
+	switch strings.ToUpper(s) {
+	case "LEFT":
+		return true, nil
+	case "RIGHT":
+		return false, nil
+	}
+	return false, errSyntax
+}
+
+func listEnd(left bool) string {
+	if left {
+		return "LEFT"
+	}
+	return "RIGHT"
+}
+
+func writeListPop(w *resp.Writer, key string, popped []string) {
+	w.WriteArrayLen(2)
+	w.WriteBulkString(key)
+	w.WriteBulkStrings(popped)
+}
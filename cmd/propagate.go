@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"go_redis/internals/aof"
+	"go_redis/internals/resp"
+	"go_redis/internals/store"
+	"log"
+	"strconv"
+)
+
+// Commands only ever run one at a time (on the server event loop, or while
+// replaying the AOF before the server starts), so the propagation state below
+// is never touched concurrently.
+var (
+	aofLog   *aof.AOF
+	rewrites [][]string
+	// aofDB is the database selected at the end of the AOF, -1 when unknown.
+	aofDB = -1
+	// inMulti is set while a transaction runs. Its MULTI is only appended
+	// with its first write, multiWritten records that it was.
+	inMulti, multiWritten bool
+	// rewriteScheduled defers a rewrite asked for inside a transaction
+	// until the transaction ends.
+	rewriteScheduled bool
+)
+
+// UseAOF makes Execute append every command that modified the store to a.
+func UseAOF(a *aof.AOF) {
+	aofLog = a
+	aofDB = -1
+}
+
+// propagateAs replaces the command being executed in the AOF with args. A
+// handler may call it several times to log more than one command.
+func propagateAs(args ...string) {
+	rewrites = append(rewrites, args)
+}
+
+func propagate(args []string, dbs store.Databases, db int) {
+	if aofLog == nil {
+		return
+	}
+
+	cmds := rewrites
+	if len(cmds) == 0 {
+		cmds = [][]string{args}
+	}
+	if inMulti && !multiWritten {
+		cmds = append([][]string{{"MULTI"}}, cmds...)
+		multiWritten = true
+	}
+	if db != aofDB {
+		cmds = append([][]string{{"SELECT", strconv.Itoa(db)}}, cmds...)
+		aofDB = db
+	}
+	for _, c := range cmds {
+		appendAOF(c)
+	}
+
+	if !inMulti {
+		maybeRewrite(dbs)
+	}
+}
+
+// Multi starts a transaction: the commands propagated until Exec reach the
+// AOF wrapped in MULTI/EXEC, so replaying a file cut short in the middle of
+// the transaction applies none of it.
+func Multi() {
+	inMulti = true
+}
+
+// Exec ends the transaction started by Multi.
+func Exec(dbs store.Databases) {
+	inMulti = false
+	if aofLog == nil {
+		return
+	}
+	if multiWritten {
+		appendAOF([]string{"EXEC"})
+		multiWritten = false
+	}
+	maybeRewrite(dbs)
+}
+
+func appendAOF(args []string) {
+	if err := aofLog.Append(args); err != nil {
+		log.Printf("[aof] Failed to append %s: %v", args[0], err)
+	}
+}
+
+func maybeRewrite(dbs store.Databases) {
+	if !rewriteScheduled && !aofLog.NeedsRewrite() {
+		return
+	}
+	rewriteScheduled = false
+	log.Printf("[aof] Starting automatic rewrite")
+	if err := rewriteAOF(dbs); err != nil {
+		log.Printf("[aof] Failed to start rewrite: %v", err)
+	}
+}
+
+func rewriteAOF(dbs store.Databases) error {
+	if err := aofLog.Rewrite(dbs.Dump()); err != nil {
+		return err
+	}
+	aofDB = -1
+	return nil
+}
+
+func handleBGRewriteAOF(args []string, dbs store.Databases, w *resp.Writer) {
+	if len(args) != 1 {
+		writeError(w, "wrong no. of arguments for 'bgrewriteaof'")
+		return
+	}
+	if aofLog == nil {
+		writeError(w, "AOF is not enabled")
+		return
+	}
+	if inMulti {
+		// Rewriting now would split the transaction between the old file
+		// and the new one.
+		rewriteScheduled = true
+		w.WriteSimpleString("Background append only file rewriting scheduled")
+		return
+	}
+	if err := rewriteAOF(dbs); err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	w.WriteSimpleString("Background append only file rewriting started")
+}
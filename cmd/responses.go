@@ -1,41 +1,47 @@
 package cmd
 
 import (
-	"fmt"
-	"net"
+	"errors"
+	"go_redis/internals/resp"
+	"go_redis/internals/store"
 )
 
-func writeOk(conn net.Conn) {
-	fmt.Fprintf(conn, "+Ok\r\n")
+func writeOk(w *resp.Writer) {
+	w.WriteSimpleString("Ok")
 }
 
-func writeString(conn net.Conn, s string) {
-	fmt.Fprintf(conn, "+%s\r\n", s)
+// WriteOk writes the same reply as a successful command, for replies
+// written at the connection level.
+func WriteOk(w *resp.Writer) {
+	writeOk(w)
 }
 
-func writeBulkString(conn net.Conn, s string) {
-	fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(s), s)
-}
-
-func writeNullBulkString(conn net.Conn) {
-	fmt.Fprintf(conn, "$-1\r\n")
-}
-
-func writeInteger(conn net.Conn, n int) {
-	fmt.Fprintf(conn, ":%d\r\n", n)
+func writeBool(w *resp.Writer, b bool) {
+	if b {
+		w.WriteInteger(1)
+	} else {
+		w.WriteInteger(0)
+	}
 }
 
-func writeError(conn net.Conn, errMsg string) {
-	fmt.Fprintf(conn, "-ERR %s\r\n", errMsg)
+func writeError(w *resp.Writer, errMsg string) {
+	w.WriteError("ERR " + errMsg)
 }
 
-func writeArray(conn net.Conn, data []string) {
-	fmt.Fprintf(conn, "*%d\r\n", len(data))
-	for _, val := range data {
-		fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(val), val)
+// writeStoreError replies with an error returned by the store. WRONGTYPE
+// carries its own prefix instead of the generic ERR.
+func writeStoreError(w *resp.Writer, err error) {
+	if errors.Is(err, store.ErrWrongType) {
+		w.WriteError(err.Error())
+		return
 	}
+	writeError(w, err.Error())
 }
 
-func writeNullArray(conn net.Conn) {
-	fmt.Fprint(conn, "*-1\r\n")
+// writeSet replies with a set, an array for RESP2 clients.
+func writeSet(w *resp.Writer, members []string) {
+	w.WriteSetLen(len(members))
+	for _, m := range members {
+		w.WriteBulkString(m)
+	}
 }
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"go_redis/internals/resp"
+	"go_redis/internals/store"
+	"strconv"
+	"strings"
+)
+
+func handleSAdd(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'sadd'")
+		return
+	}
+	added, err := s.SAdd(args[1], args[2:]...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(added)
+}
+
+func handleSRem(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'srem'")
+		return
+	}
+	removed, err := s.SRem(args[1], args[2:]...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(removed)
+}
+
+func handleSMembers(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'smembers'")
+		return
+	}
+	members, err := s.SMembers(args[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeSet(w, members)
+}
+
+func handleSIsMember(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'sismember'")
+		return
+	}
+	ok, err := s.SIsMember(args[1], args[2])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeBool(w, ok)
+}
+
+func handleSMIsMember(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'smismember'")
+		return
+	}
+	result, err := s.SMIsMember(args[1], args[2:]...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteArrayLen(len(result))
+	for _, ok := range result {
+		writeBool(w, ok)
+	}
+}
+
+func handleSCard(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'scard'")
+		return
+	}
+	n, err := s.SCard(args[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleSPop(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 && len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'spop'")
+		return
+	}
+
+	count := 1
+	if len(args) == 3 {
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n < 0 {
+			writeError(w, "value is out of range, must be positive")
+			return
+		}
+		count = n
+	}
+
+	popped, err := s.SPop(args[1], count)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if len(popped) > 0 {
+		propagateAs(append([]string{"SREM", args[1]}, popped...)...)
+	}
+
+	if len(args) == 3 {
+		writeSet(w, popped)
+	} else if len(popped) == 0 {
+		w.WriteNull()
+	} else {
+		w.WriteBulkString(popped[0])
+	}
+}
+
+func handleSRandMember(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 && len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'srandmember'")
+		return
+	}
+
+	count := 1
+	if len(args) == 3 {
+		n, err := parseRandomCount(args[2])
+		if err != nil {
+			writeError(w, err.Error())
+			return
+		}
+		count = n
+	}
+
+	members, err := s.SRandMember(args[1], count)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if len(args) == 3 {
+		w.WriteBulkStrings(members)
+	} else if len(members) == 0 {
+		w.WriteNull()
+	} else {
+		w.WriteBulkString(members[0])
+	}
+}
+
+func handleSMove(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'smove'")
+		return
+	}
+	moved, err := s.SMove(args[1], args[2], args[3])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeBool(w, moved)
+}
+
+func handleSetAlgebra(args []string, w *resp.Writer, fn func(keys ...string) ([]string, error)) {
+	if len(args) < 2 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+	members, err := fn(args[1:]...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeSet(w, members)
+}
+
+func handleSetAlgebraStore(args []string, w *resp.Writer, fn func(dst string, keys ...string) (int, error)) {
+	if len(args) < 3 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+	n, err := fn(args[1], args[2:]...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleSScan(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'sscan'")
+		return
+	}
+	cursor, opts, err := parseScan(args[2:], false)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	next, members, err := s.SScan(args[1], cursor, opts)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeScanReply(w, next, members)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"go_redis/internals/resp"
+	"go_redis/internals/snapshot"
+	"go_redis/internals/store"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	snapshotPath string
+	lastSave     atomic.Int64
+	bgSaving     atomic.Bool
+)
+
+// UseSnapshot sets the file SAVE and BGSAVE write to.
+func UseSnapshot(path string) {
+	snapshotPath = path
+	lastSave.Store(time.Now().Unix())
+}
+
+func handleSave(args []string, dbs store.Databases, w *resp.Writer) {
+	if len(args) != 1 {
+		writeError(w, "wrong no. of arguments for 'save'")
+		return
+	}
+	if bgSaving.Load() {
+		writeError(w, "Background save already in progress")
+		return
+	}
+	if err := snapshot.Save(snapshotPath, dbs.Dump()); err != nil {
+		log.Printf("[snapshot] SAVE failed: %v", err)
+		writeError(w, err.Error())
+		return
+	}
+	lastSave.Store(time.Now().Unix())
+	writeOk(w)
+}
+
+func handleBGSave(args []string, dbs store.Databases, w *resp.Writer) {
+	if len(args) != 1 {
+		writeError(w, "wrong no. of arguments for 'bgsave'")
+		return
+	}
+	if !bgSaving.CompareAndSwap(false, true) {
+		writeError(w, "Background save already in progress")
+		return
+	}
+
+	entries := dbs.Dump()
+	go func() {
+		defer bgSaving.Store(false)
+
+		start := time.Now()
+		if err := snapshot.Save(snapshotPath, entries); err != nil {
+			log.Printf("[snapshot] BGSAVE failed: %v", err)
+			return
+		}
+		lastSave.Store(time.Now().Unix())
+		log.Printf("[snapshot] Saved %d keys to %s in %v", len(entries), snapshotPath, time.Since(start))
+	}()
+
+	w.WriteSimpleString("Background saving started")
+}
+
+func handleLastSave(args []string, w *resp.Writer) {
+	if len(args) != 1 {
+		writeError(w, "wrong no. of arguments for 'lastsave'")
+		return
+	}
+	w.WriteInteger(int(lastSave.Load()))
+}
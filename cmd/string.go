@@ -0,0 +1,456 @@
+package cmd
+
+import (
+	"fmt"
+	"go_redis/internals/resp"
+	"go_redis/internals/store"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func handleSet(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'set'")
+		return
+	}
+
+	var opts store.SetOptions
+	expires := false
+	for i := 3; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); option {
+		case "NX":
+			opts.NX = true
+		case "XX":
+			opts.XX = true
+		case "GET":
+			opts.Get = true
+		case "KEEPTTL":
+			opts.KeepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if expires || i+1 >= len(args) {
+				writeError(w, errSyntax.Error())
+				return
+			}
+			at, err := parseExpireTime(option, args[i+1], "set")
+			if err != nil {
+				writeError(w, err.Error())
+				return
+			}
+			opts.ExpireAt = at
+			expires = true
+			i++
+		default:
+			writeError(w, errSyntax.Error())
+			return
+		}
+	}
+	if (opts.NX && opts.XX) || (opts.KeepTTL && expires) {
+		writeError(w, errSyntax.Error())
+		return
+	}
+
+	key, val := args[1], args[2]
+	old, hadOld, written, err := s.SetWith(key, val, opts)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if written {
+		switch {
+		case expires:
+			propagateAs("SET", key, val, "PXAT", strconv.FormatInt(opts.ExpireAt.UnixMilli(), 10))
+		case opts.KeepTTL:
+			propagateAs("SET", key, val, "KEEPTTL")
+		default:
+			propagateAs("SET", key, val)
+		}
+	}
+
+	switch {
+	case opts.Get && hadOld:
+		w.WriteBulkString(old)
+	case opts.Get, !written:
+		w.WriteNull()
+	default:
+		writeOk(w)
+	}
+}
+
+func handleSetNX(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'setnx'")
+		return
+	}
+	_, _, written, _ := s.SetWith(args[1], args[2], store.SetOptions{NX: true})
+	writeBool(w, written)
+}
+
+// handleSetEx implements SETEX and PSETEX, option being the SET option
+// matching their TTL unit.
+func handleSetEx(args []string, s *store.Store, w *resp.Writer, option string) {
+	name := strings.ToLower(args[0])
+	if len(args) != 4 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", name))
+		return
+	}
+	at, err := parseExpireTime(option, args[2], name)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	key, val := args[1], args[3]
+	s.SetWith(key, val, store.SetOptions{ExpireAt: at})
+	propagateAs("SET", key, val, "PXAT", strconv.FormatInt(at.UnixMilli(), 10))
+	writeOk(w)
+}
+
+func handleGetSet(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'getset'")
+		return
+	}
+	old, ok, err := s.GetSet(args[1], args[2])
+	writeStringReply(w, old, ok, err)
+}
+
+func handleGetDel(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'getdel'")
+		return
+	}
+	val, ok, err := s.GetDel(args[1])
+	writeStringReply(w, val, ok, err)
+}
+
+func handleGetEx(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 2 {
+		writeError(w, "wrong no. of arguments for 'getex'")
+		return
+	}
+
+	var at time.Time
+	persist := false
+	switch {
+	case len(args) == 2:
+	case len(args) == 3 && strings.ToUpper(args[2]) == "PERSIST":
+		persist = true
+	case len(args) == 4:
+		option := strings.ToUpper(args[2])
+		switch option {
+		case "EX", "PX", "EXAT", "PXAT":
+		default:
+			writeError(w, errSyntax.Error())
+			return
+		}
+		var err error
+		if at, err = parseExpireTime(option, args[3], "getex"); err != nil {
+			writeError(w, err.Error())
+			return
+		}
+	default:
+		writeError(w, errSyntax.Error())
+		return
+	}
+
+	val, ok, err := s.GetEx(args[1], at, persist)
+	if ok && !at.IsZero() {
+		propagateAs("PEXPIREAT", args[1], strconv.FormatInt(at.UnixMilli(), 10))
+	}
+	writeStringReply(w, val, ok, err)
+}
+
+func handleGet(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'get'")
+		return
+	}
+	val, ok, err := s.Get(args[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if ok {
+		w.WriteBulkString(val)
+	} else {
+		w.WriteNull()
+	}
+}
+
+func handleMSet(args []string, s *store.Store, w *resp.Writer) {
+	if len(args)%2 != 1 {
+		writeError(w, "wrong no. of arguments for 'mset'")
+		return
+	}
+	for i := 1; i < len(args); i += 2 {
+		key := args[i]
+		val := args[i+1]
+		s.Set(key, val)
+	}
+	writeOk(w)
+}
+
+func handleMGet(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 2 {
+		writeError(w, "wrong no. of arguments for 'mget'")
+		return
+	}
+	w.WriteArrayLen(len(args) - 1)
+	for _, key := range args[1:] {
+		if val, ok, _ := s.Get(key); ok {
+			w.WriteBulkString(val)
+		} else {
+			w.WriteNull()
+		}
+	}
+}
+
+// handleIncr implements INCR, DECR, INCRBY and DECRBY. sign is -1 for the
+// decrementing forms and by whether the amount is an argument.
+func handleIncr(args []string, s *store.Store, w *resp.Writer, sign int64, by bool) {
+	name := strings.ToLower(args[0])
+	if (by && len(args) != 3) || (!by && len(args) != 2) {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", name))
+		return
+	}
+
+	delta := int64(1)
+	if by {
+		n, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			writeError(w, errNotInteger.Error())
+			return
+		}
+		if sign < 0 && n == math.MinInt64 {
+			writeError(w, "decrement would overflow")
+			return
+		}
+		delta = n
+	}
+
+	n, err := s.IncrBy(args[1], sign*delta)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(int(n))
+}
+
+func handleIncrByFloat(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'incrbyfloat'")
+		return
+	}
+	incr, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || math.IsNaN(incr) || math.IsInf(incr, 0) {
+		writeError(w, errNotFloat.Error())
+		return
+	}
+	val, err := s.IncrByFloat(args[1], incr)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	// Replaying the addition could round differently, so log the result.
+	propagateAs("SET", args[1], val, "KEEPTTL")
+	w.WriteBulkString(val)
+}
+
+func handleMSetNX(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 || len(args)%2 != 1 {
+		writeError(w, "wrong no. of arguments for 'msetnx'")
+		return
+	}
+	writeBool(w, s.MSetNX(args[1:]...))
+}
+
+func handleAppend(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'append'")
+		return
+	}
+	n, err := s.Append(args[1], args[2])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleStrLen(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'strlen'")
+		return
+	}
+	n, err := s.StrLen(args[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleGetRange(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'getrange'")
+		return
+	}
+	start, err1 := strconv.Atoi(args[2])
+	end, err2 := strconv.Atoi(args[3])
+	if err1 != nil || err2 != nil {
+		writeError(w, errNotInteger.Error())
+		return
+	}
+	val, err := s.GetRange(args[1], start, end)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteBulkString(val)
+}
+
+func handleSetRange(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'setrange'")
+		return
+	}
+	offset, err := strconv.Atoi(args[2])
+	if err != nil {
+		writeError(w, errNotInteger.Error())
+		return
+	}
+	if offset < 0 {
+		writeError(w, "offset is out of range")
+		return
+	}
+	n, err := s.SetRange(args[1], offset, args[3])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleLCS(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'lcs'")
+		return
+	}
+
+	var lenOnly, idx, withMatchLen bool
+	minMatchLen := 0
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "LEN":
+			lenOnly = true
+		case "IDX":
+			idx = true
+		case "WITHMATCHLEN":
+			withMatchLen = true
+		case "MINMATCHLEN":
+			if i+1 >= len(args) {
+				writeError(w, errSyntax.Error())
+				return
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				writeError(w, errNotInteger.Error())
+				return
+			}
+			minMatchLen = max(n, 0)
+			i++
+		default:
+			writeError(w, errSyntax.Error())
+			return
+		}
+	}
+	if lenOnly && idx {
+		writeError(w, "If you want both the length and indexes, please just use IDX.")
+		return
+	}
+
+	lcs, matches, err := s.LCS(args[1], args[2])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	switch {
+	case lenOnly:
+		w.WriteInteger(len(lcs))
+	case idx:
+		var kept []store.LCSMatch
+		for _, m := range matches {
+			if m.Len >= minMatchLen {
+				kept = append(kept, m)
+			}
+		}
+		w.WriteMapLen(2)
+		w.WriteBulkString("matches")
+		w.WriteArrayLen(len(kept))
+		for _, m := range kept {
+			if withMatchLen {
+				w.WriteArrayLen(3)
+			} else {
+				w.WriteArrayLen(2)
+			}
+			w.WriteArrayLen(2)
+			w.WriteInteger(m.A[0])
+			w.WriteInteger(m.A[1])
+			w.WriteArrayLen(2)
+			w.WriteInteger(m.B[0])
+			w.WriteInteger(m.B[1])
+			if withMatchLen {
+				w.WriteInteger(m.Len)
+			}
+		}
+		w.WriteBulkString("len")
+		w.WriteInteger(len(lcs))
+	default:
+		w.WriteBulkString(lcs)
+	}
+}
+
+// parseExpireTime turns the argument of an EX, PX, EXAT or PXAT option into
+// an absolute expiry time.
+func parseExpireTime(option, arg, command string) (time.Time, error) {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return time.Time{}, errNotInteger
+	}
+	invalid := fmt.Errorf("invalid expire time in '%s' command", command)
+	if n <= 0 {
+		return time.Time{}, invalid
+	}
+
+	switch option {
+	case "EX", "EXAT":
+		if n > math.MaxInt64/1000 {
+			return time.Time{}, invalid
+		}
+		n *= 1000
+	}
+	switch option {
+	case "EX", "PX":
+		now := time.Now().UnixMilli()
+		if n > math.MaxInt64-now {
+			return time.Time{}, invalid
+		}
+		n += now
+	}
+	return time.UnixMilli(n), nil
+}
+
+// writeStringReply writes a string looked up in the store, or a null bulk
+// string if it was missing.
+func writeStringReply(w *resp.Writer, val string, ok bool, err error) {
+	switch {
+	case err != nil:
+		writeStoreError(w, err)
+	case !ok:
+		w.WriteNull()
+	default:
+		w.WriteBulkString(val)
+	}
+}
@@ -0,0 +1,442 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"go_redis/internals/resp"
+	"go_redis/internals/store"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	errSyntax       = errors.New("syntax error")
+	errNotFloat     = errors.New("value is not a valid float")
+	errScoreRange   = errors.New("min or max is not a float")
+	errLexRange     = errors.New("min or max not valid string range item")
+	errNotInteger   = errors.New("value is not an integer or out of range")
+	errLimitByRank  = errors.New("syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	errLexAndScores = errors.New("syntax error, WITHSCORES not supported in combination with BYLEX")
+)
+
+func handleZAdd(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 4 {
+		writeError(w, "wrong no. of arguments for 'zadd'")
+		return
+	}
+
+	var opts store.ZAddOptions
+	var ch, incr bool
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			opts.NX = true
+		case "XX":
+			opts.XX = true
+		case "GT":
+			opts.GT = true
+		case "LT":
+			opts.LT = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		default:
+			break options
+		}
+	}
+
+	rest := args[i:]
+	if len(rest) == 0 || len(rest)%2 != 0 {
+		writeError(w, errSyntax.Error())
+		return
+	}
+	if opts.NX && opts.XX {
+		writeError(w, "XX and NX options at the same time are not compatible")
+		return
+	}
+	if (opts.GT && opts.LT) || (opts.NX && (opts.GT || opts.LT)) {
+		writeError(w, "GT, LT, and/or NX options at the same time are not compatible")
+		return
+	}
+	if incr && len(rest) != 2 {
+		writeError(w, "INCR option supports a single increment-element pair")
+		return
+	}
+
+	members := make([]store.ZMember, 0, len(rest)/2)
+	for j := 0; j < len(rest); j += 2 {
+		score, err := parseScore(rest[j])
+		if err != nil {
+			writeError(w, err.Error())
+			return
+		}
+		members = append(members, store.ZMember{Member: rest[j+1], Score: score})
+	}
+
+	if incr {
+		score, ok, err := s.ZIncrBy(args[1], members[0].Member, members[0].Score, opts)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if !ok {
+			w.WriteNull()
+			return
+		}
+		w.WriteDouble(score)
+		return
+	}
+
+	added, updated, err := s.ZAdd(args[1], members, opts)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if ch {
+		w.WriteInteger(added + updated)
+	} else {
+		w.WriteInteger(added)
+	}
+}
+
+func handleZIncrBy(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'zincrby'")
+		return
+	}
+	incr, err := parseScore(args[2])
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	score, _, err := s.ZIncrBy(args[1], args[3], incr, store.ZAddOptions{})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteDouble(score)
+}
+
+func handleZRem(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'zrem'")
+		return
+	}
+	removed, err := s.ZRem(args[1], args[2:]...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(removed)
+}
+
+func handleZScore(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 3 {
+		writeError(w, "wrong no. of arguments for 'zscore'")
+		return
+	}
+	score, ok, err := s.ZScore(args[1], args[2])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !ok {
+		w.WriteNull()
+		return
+	}
+	w.WriteDouble(score)
+}
+
+func handleZCard(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 2 {
+		writeError(w, "wrong no. of arguments for 'zcard'")
+		return
+	}
+	n, err := s.ZCard(args[1])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleZRank(args []string, s *store.Store, w *resp.Writer, rev bool) {
+	withScore := len(args) == 4 && strings.ToUpper(args[3]) == "WITHSCORE"
+	if len(args) != 3 && !withScore {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+
+	rank, score, ok, err := s.ZRank(args[1], args[2], rev)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !ok {
+		if withScore {
+			w.WriteNullArray()
+		} else {
+			w.WriteNull()
+		}
+		return
+	}
+	if withScore {
+		w.WriteArrayLen(2)
+		w.WriteInteger(rank)
+		w.WriteDouble(score)
+		return
+	}
+	w.WriteInteger(rank)
+}
+
+func handleZRange(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 4 {
+		writeError(w, "wrong no. of arguments for 'zrange'")
+		return
+	}
+	spec, withScores, err := parseZRange(args[2:], true)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	members, err := s.ZRange(args[1], spec)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeZMembers(w, members, withScores)
+}
+
+func handleZRangeStore(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 5 {
+		writeError(w, "wrong no. of arguments for 'zrangestore'")
+		return
+	}
+	spec, _, err := parseZRange(args[3:], false)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	n, err := s.ZRangeStore(args[1], args[2], spec)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleZCount(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) != 4 {
+		writeError(w, "wrong no. of arguments for 'zcount'")
+		return
+	}
+	r, err := parseScoreRange(args[2], args[3])
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	n, err := s.ZCount(args[1], r)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteInteger(n)
+}
+
+func handleZPop(args []string, s *store.Store, w *resp.Writer, max bool) {
+	if len(args) != 2 && len(args) != 3 {
+		writeError(w, fmt.Sprintf("wrong no. of arguments for '%s'", strings.ToLower(args[0])))
+		return
+	}
+
+	count := 1
+	if len(args) == 3 {
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n < 0 {
+			writeError(w, "value is out of range, must be positive")
+			return
+		}
+		count = n
+	}
+
+	popped, err := s.ZPop(args[1], count, max)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeZMembers(w, popped, true)
+}
+
+// parseZRange parses "<min> <max> [BYSCORE|BYLEX] [REV] [LIMIT offset count]
+// [WITHSCORES]" as accepted by ZRANGE and ZRANGESTORE.
+func parseZRange(args []string, allowWithScores bool) (store.ZRangeSpec, bool, error) {
+	spec := store.ZRangeSpec{Count: -1}
+	withScores, limit := false, false
+
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BYSCORE":
+			spec.By = store.ZRangeByScore
+		case "BYLEX":
+			spec.By = store.ZRangeByLex
+		case "REV":
+			spec.Rev = true
+		case "WITHSCORES":
+			if !allowWithScores {
+				return spec, false, errSyntax
+			}
+			withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return spec, false, errSyntax
+			}
+			offset, err1 := strconv.Atoi(args[i+1])
+			count, err2 := strconv.Atoi(args[i+2])
+			if err1 != nil || err2 != nil {
+				return spec, false, errNotInteger
+			}
+			spec.Offset, spec.Count = offset, count
+			limit = true
+			i += 2
+		default:
+			return spec, false, errSyntax
+		}
+	}
+
+	if limit && spec.By == store.ZRangeByRank {
+		return spec, false, errLimitByRank
+	}
+	if withScores && spec.By == store.ZRangeByLex {
+		return spec, false, errLexAndScores
+	}
+
+	// With REV the score and lex forms take the bounds as "<max> <min>".
+	min, max := args[0], args[1]
+	if spec.Rev && spec.By != store.ZRangeByRank {
+		min, max = max, min
+	}
+
+	var err error
+	switch spec.By {
+	case store.ZRangeByRank:
+		var err1, err2 error
+		spec.Start, err1 = strconv.Atoi(min)
+		spec.Stop, err2 = strconv.Atoi(max)
+		if err1 != nil || err2 != nil {
+			err = errNotInteger
+		}
+	case store.ZRangeByScore:
+		spec.Score, err = parseScoreRange(min, max)
+	case store.ZRangeByLex:
+		spec.Lex, err = parseLexRange(min, max)
+	}
+	if spec.Offset < 0 {
+		spec.Count = 0
+	}
+	return spec, withScores, err
+}
+
+func parseScore(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, errNotFloat
+	}
+	return f, nil
+}
+
+func parseScoreRange(min, max string) (store.ScoreRange, error) {
+	var r store.ScoreRange
+	var err1, err2 error
+	r.Min, r.MinExclusive, err1 = parseScoreBound(min)
+	r.Max, r.MaxExclusive, err2 = parseScoreBound(max)
+	if err1 != nil || err2 != nil {
+		return r, errScoreRange
+	}
+	return r, nil
+}
+
+func parseScoreBound(s string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	f, err := parseScore(s)
+	return f, exclusive, err
+}
+
+func parseLexRange(min, max string) (store.LexRange, error) {
+	var r store.LexRange
+	var err1, err2 error
+	r.Min, err1 = parseLexBound(min)
+	r.Max, err2 = parseLexBound(max)
+	if err1 != nil || err2 != nil {
+		return r, errLexRange
+	}
+	return r, nil
+}
+
+func parseLexBound(s string) (store.LexBound, error) {
+	switch {
+	case s == "-":
+		return store.LexBound{Inf: -1}, nil
+	case s == "+":
+		return store.LexBound{Inf: 1}, nil
+	case strings.HasPrefix(s, "["):
+		return store.LexBound{Value: s[1:]}, nil
+	case strings.HasPrefix(s, "("):
+		return store.LexBound{Value: s[1:], Exclusive: true}, nil
+	default:
+		return store.LexBound{}, errLexRange
+	}
+}
+
+// writeZMembers replies with members, followed by their scores if
+// withScores is set. RESP3 clients get each member and score as a pair.
+func writeZMembers(w *resp.Writer, members []store.ZMember, withScores bool) {
+	if withScores && w.Protocol() >= 3 {
+		w.WriteArrayLen(len(members))
+		for _, m := range members {
+			w.WriteArrayLen(2)
+			w.WriteBulkString(m.Member)
+			w.WriteDouble(m.Score)
+		}
+		return
+	}
+	if withScores {
+		w.WriteArrayLen(len(members) * 2)
+	} else {
+		w.WriteArrayLen(len(members))
+	}
+	for _, m := range members {
+		w.WriteBulkString(m.Member)
+		if withScores {
+			w.WriteBulkString(resp.FormatDouble(m.Score))
+		}
+	}
+}
+
+func handleZScan(args []string, s *store.Store, w *resp.Writer) {
+	if len(args) < 3 {
+		writeError(w, "wrong no. of arguments for 'zscan'")
+		return
+	}
+	cursor, opts, err := parseScan(args[2:], false)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	next, members, err := s.ZScan(args[1], cursor, opts)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	items := make([]string, 0, len(members)*2)
+	for _, m := range members {
+		items = append(items, m.Member, resp.FormatDouble(m.Score))
+	}
+	writeScanReply(w, next, items)
+}
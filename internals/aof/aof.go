@@ -0,0 +1,282 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go_redis/internals/resp"
+)
+
+type FsyncPolicy int
+
+const (
+	FsyncAlways FsyncPolicy = iota
+	FsyncEverySec
+	FsyncNo
+)
+
+var ErrInvalidPolicy = errors.New("invalid appendfsync policy")
+
+func ParsePolicy(s string) (FsyncPolicy, error) {
+	switch s {
+	case "always":
+		return FsyncAlways, nil
+	case "everysec":
+		return FsyncEverySec, nil
+	case "no":
+		return FsyncNo, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrInvalidPolicy, s)
+	}
+}
+
+func (p FsyncPolicy) String() string {
+	switch p {
+	case FsyncAlways:
+		return "always"
+	case FsyncEverySec:
+		return "everysec"
+	default:
+		return "no"
+	}
+}
+
+// AOF is an append-only log of write commands stored as RESP arrays.
+type AOF struct {
+	path   string
+	policy FsyncPolicy
+	file   *os.File
+	mu     sync.Mutex
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	size              int64
+	baseSize          int64
+	rewriting         bool
+	rewriteBuf        []byte
+	rewritePercentage int
+	rewriteMinSize    int64
+}
+
+func Open(path string, policy FsyncPolicy) (*AOF, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	a := &AOF{
+		path:     path,
+		policy:   policy,
+		file:     f,
+		done:     make(chan struct{}),
+		size:     info.Size(),
+		baseSize: info.Size(),
+	}
+
+	if policy == FsyncEverySec {
+		a.wg.Add(1)
+		go a.syncLoop()
+	}
+	return a, nil
+}
+
+func (a *AOF) Path() string {
+	return a.path
+}
+
+// Append writes a single command to the log and syncs it according to the
+// configured fsync policy.
+func (a *AOF) Append(args []string) error {
+	buf := Encode(args)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	n, err := a.file.Write(buf)
+	a.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if a.rewriting {
+		a.rewriteBuf = append(a.rewriteBuf, buf...)
+	}
+	if a.policy == FsyncAlways {
+		return a.file.Sync()
+	}
+	return nil
+}
+
+// Load replays every command in the log through fn. A command cut short by a
+// crash at the end of the file is dropped and the file truncated to the last
+// complete command, anything else malformed is reported as an error.
+// Commands between MULTI and EXEC are only replayed once the EXEC is read,
+// so a transaction left unfinished at the end of the file is dropped too.
+func (a *AOF) Load(fn func(args []string)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := a.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	cr := &countingReader{r: a.file}
+	br := bufio.NewReader(cr)
+	reader := resp.NewResp(br)
+
+	var offset int64
+	count := 0
+	// tx holds the commands of an open transaction, which started at
+	// txOffset.
+	var tx [][]string
+	var txOffset int64
+	for {
+		val, err := reader.ReadValue()
+		if err != nil {
+			consumed := cr.n - int64(br.Buffered())
+			if err == io.EOF && consumed == offset {
+				break
+			}
+			if cr.eof && br.Buffered() == 0 {
+				log.Printf("[aof] Truncated command at offset %d, discarding tail", offset)
+				if err := a.truncate(offset); err != nil {
+					return err
+				}
+				break
+			}
+			return fmt.Errorf("aof: bad command at offset %d: %w", offset, err)
+		}
+
+		if val.Typ != "array" {
+			return fmt.Errorf("aof: bad command at offset %d: expected array", offset)
+		}
+		start := offset
+		offset = cr.n - int64(br.Buffered())
+
+		args := make([]string, 0, len(val.Array))
+		for _, v := range val.Array {
+			args = append(args, v.Bulk)
+		}
+		name := ""
+		if len(args) > 0 {
+			name = args[0]
+		}
+		switch {
+		case strings.EqualFold(name, "MULTI"):
+			tx, txOffset = [][]string{}, start
+		case strings.EqualFold(name, "EXEC") && tx != nil:
+			for _, c := range tx {
+				fn(c)
+			}
+			count += len(tx)
+			tx = nil
+		case tx != nil:
+			tx = append(tx, args)
+		default:
+			fn(args)
+			count++
+		}
+	}
+
+	if tx != nil {
+		log.Printf("[aof] Unfinished transaction at offset %d, discarding tail", txOffset)
+		if err := a.truncate(txOffset); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[aof] Loaded %d commands from %s", count, a.path)
+	return nil
+}
+
+// truncate cuts the log at offset, dropping whatever a crash left
+// incomplete after it.
+func (a *AOF) truncate(offset int64) error {
+	if err := a.file.Truncate(offset); err != nil {
+		return err
+	}
+	a.size = offset
+	a.baseSize = offset
+	return nil
+}
+
+func (a *AOF) Sync() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Sync()
+}
+
+func (a *AOF) Close() error {
+	close(a.done)
+	a.wg.Wait()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.file.Sync(); err != nil {
+		a.file.Close()
+		return err
+	}
+	return a.file.Close()
+}
+
+func (a *AOF) syncLoop() {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.Sync(); err != nil {
+				log.Printf("[aof] fsync failed: %v", err)
+			}
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// Encode serializes a command as a RESP array of bulk strings.
+func Encode(args []string) []byte {
+	buf := make([]byte, 0, 16*len(args)+16)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	eof bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if err == io.EOF {
+		c.eof = true
+	}
+	return n, err
+}
@@ -0,0 +1,185 @@
+package aof
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go_redis/internals/store"
+)
+
+func TestAOFAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+
+	a, err := Open(path, FsyncAlways)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	want := [][]string{
+		{"SET", "foo", "bar"},
+		{"RPUSH", "list", "a", "b c", ""},
+		{"DEL", "foo"},
+	}
+	for _, args := range want {
+		if err := a.Append(args); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	a, err = Open(path, FsyncNo)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer a.Close()
+
+	var got [][]string
+	if err := a.Load(func(args []string) { got = append(got, args) }); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestAOFLoadTruncatedTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+
+	complete := Encode([]string{"SET", "foo", "bar"})
+	partial := Encode([]string{"SET", "baz", "qux"})
+	partial = partial[:len(partial)-4]
+	if err := os.WriteFile(path, append(complete, partial...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Open(path, FsyncNo)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer a.Close()
+
+	var got [][]string
+	if err := a.Load(func(args []string) { got = append(got, args) }); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(got) != 1 || got[0][1] != "foo" {
+		t.Fatalf("expected only the complete command, got %v", got)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(complete)) {
+		t.Errorf("expected file truncated to %d bytes, got %d", len(complete), info.Size())
+	}
+}
+
+func TestAOFLoadTransactions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+
+	var data []byte
+	for _, args := range [][]string{
+		{"SET", "a", "1"},
+		{"MULTI"}, {"INCR", "a"}, {"SET", "b", "2"}, {"EXEC"},
+		{"SET", "c", "3"},
+	} {
+		data = append(data, Encode(args)...)
+	}
+	complete := len(data)
+	// A crash before EXEC leaves the last transaction unfinished.
+	data = append(data, Encode([]string{"MULTI"})...)
+	data = append(data, Encode([]string{"DEL", "a"})...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Open(path, FsyncNo)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer a.Close()
+
+	var got [][]string
+	if err := a.Load(func(args []string) { got = append(got, args) }); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	want := [][]string{{"SET", "a", "1"}, {"INCR", "a"}, {"SET", "b", "2"}, {"SET", "c", "3"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(complete) {
+		t.Errorf("expected file truncated to %d bytes, got %d", complete, info.Size())
+	}
+}
+
+func TestAOFLoadCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+
+	data := append(Encode([]string{"SET", "foo", "bar"}), "?garbage\r\n"...)
+	data = append(data, Encode([]string{"DEL", "foo"})...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Open(path, FsyncNo)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer a.Close()
+
+	if err := a.Load(func(args []string) {}); err == nil {
+		t.Fatal("expected error for corrupt log, got nil")
+	}
+}
+
+func TestAOFRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+
+	a, err := Open(path, FsyncNo)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		a.Append([]string{"LPUSH", "list", "x"})
+		a.Append([]string{"LPOP", "list"})
+	}
+	a.Append([]string{"SET", "foo", "bar"})
+
+	entries := []store.Entry{{Key: "foo", Type: store.TypeString, Str: "bar"}}
+	if err := a.Rewrite(entries); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if err := a.Append([]string{"SET", "after", "rewrite"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	a, err = Open(path, FsyncNo)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer a.Close()
+
+	var got [][]string
+	if err := a.Load(func(args []string) { got = append(got, args) }); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	want := [][]string{
+		{"SET", "foo", "bar"},
+		{"SET", "after", "rewrite"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,197 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go_redis/internals/store"
+)
+
+// itemsPerCommand caps how many list elements or hash fields a single
+// rewritten command carries, so huge keys do not become one giant command.
+const itemsPerCommand = 64
+
+var ErrRewriteInProgress = errors.New("background AOF rewrite already in progress")
+
+// SetAutoRewrite enables automatic rewrites once the log has grown by
+// percentage since the last rewrite and is at least minSize bytes. A
+// percentage of 0 disables automatic rewrites.
+func (a *AOF) SetAutoRewrite(percentage int, minSize int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rewritePercentage = percentage
+	a.rewriteMinSize = minSize
+}
+
+// NeedsRewrite reports whether the automatic rewrite thresholds are met.
+func (a *AOF) NeedsRewrite() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.rewriting || a.rewritePercentage <= 0 || a.size < a.rewriteMinSize {
+		return false
+	}
+	base := max(a.baseSize, 1)
+	return (a.size-base)*100/base >= int64(a.rewritePercentage)
+}
+
+func (a *AOF) Rewriting() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rewriting
+}
+
+// Rewrite replaces the log in the background with the minimal set of
+// commands that recreate entries. Commands appended while the rewrite runs
+// are buffered and added to the new log before it is swapped in, so entries
+// must be taken from the store at the moment Rewrite is called. The new log
+// may end with any database selected, so the first command appended after
+// Rewrite must be preceded by a SELECT.
+func (a *AOF) Rewrite(entries []store.Entry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.rewriting {
+		return ErrRewriteInProgress
+	}
+	a.rewriting = true
+	a.rewriteBuf = nil
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		start := time.Now()
+		if err := a.rewrite(entries); err != nil {
+			log.Printf("[aof] Rewrite failed: %v", err)
+			a.mu.Lock()
+			a.rewriting = false
+			a.rewriteBuf = nil
+			a.mu.Unlock()
+			return
+		}
+		log.Printf("[aof] Rewrote %s with %d keys in %v", a.path, len(entries), time.Since(start))
+	}()
+	return nil
+}
+
+func (a *AOF) rewrite(entries []store.Entry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), "temp-rewrite-*.aof")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	bw := bufio.NewWriter(tmp)
+	db := 0
+	for _, e := range entries {
+		if e.DB != db {
+			if _, err := bw.Write(Encode([]string{"SELECT", strconv.Itoa(e.DB)})); err != nil {
+				return err
+			}
+			db = e.DB
+		}
+		for _, c := range Commands(e) {
+			if _, err := bw.Write(Encode(c)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := tmp.Write(a.rewriteBuf); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), a.path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(a.path, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	a.file.Close()
+	a.file = f
+	a.size = info.Size()
+	a.baseSize = a.size
+	a.rewriting = false
+	a.rewriteBuf = nil
+	return nil
+}
+
+// Commands returns the commands that recreate e from an empty store.
+func Commands(e store.Entry) [][]string {
+	var cmds [][]string
+
+	switch e.Type {
+	case store.TypeString:
+		cmds = append(cmds, []string{"SET", e.Key, e.Str})
+
+	case store.TypeList:
+		for i := 0; i < len(e.List); i += itemsPerCommand {
+			chunk := e.List[i:min(i+itemsPerCommand, len(e.List))]
+			cmds = append(cmds, append([]string{"RPUSH", e.Key}, chunk...))
+		}
+
+	case store.TypeHash:
+		c := []string{"HSET", e.Key}
+		for f, v := range e.Hash {
+			c = append(c, f, v)
+			if len(c) == 2+2*itemsPerCommand {
+				cmds = append(cmds, c)
+				c = []string{"HSET", e.Key}
+			}
+		}
+		if len(c) > 2 {
+			cmds = append(cmds, c)
+		}
+
+	case store.TypeSet:
+		for i := 0; i < len(e.Set); i += itemsPerCommand {
+			chunk := e.Set[i:min(i+itemsPerCommand, len(e.Set))]
+			cmds = append(cmds, append([]string{"SADD", e.Key}, chunk...))
+		}
+
+	case store.TypeZSet:
+		c := []string{"ZADD", e.Key}
+		for _, m := range e.ZSet {
+			c = append(c, strconv.FormatFloat(m.Score, 'g', -1, 64), m.Member)
+			if len(c) == 2+2*itemsPerCommand {
+				cmds = append(cmds, c)
+				c = []string{"ZADD", e.Key}
+			}
+		}
+		if len(c) > 2 {
+			cmds = append(cmds, c)
+		}
+	}
+
+	for f, exp := range e.HashExpiries {
+		cmds = append(cmds, []string{"HPEXPIREAT", e.Key, strconv.FormatInt(exp.UnixMilli(), 10), "FIELDS", "1", f})
+	}
+	if !e.Expiry.IsZero() {
+		cmds = append(cmds, []string{"PEXPIREAT", e.Key, strconv.FormatInt(e.Expiry.UnixMilli(), 10)})
+	}
+	return cmds
+}
@@ -0,0 +1,94 @@
+package glob
+
+// Match reports whether s matches the Redis-style glob pattern. It supports
+// '*', '?', character classes such as "[abc]", "[^a]" and "[a-z]", and
+// backslash escapes. Unlike path.Match, '*' also matches '/'.
+func Match(pattern, s string) bool {
+	// On a mismatch the most recent '*' swallows one more byte and matching
+	// resumes after it. Earlier stars never need revisiting, which keeps
+	// matching linear in len(pattern)*len(s) rather than exponential.
+	px, sx := 0, 0
+	star, starS := -1, 0
+	for px < len(pattern) || sx < len(s) {
+		if px < len(pattern) {
+			switch c := pattern[px]; c {
+			case '*':
+				star, starS = px, sx
+				px++
+				continue
+
+			case '?':
+				if sx < len(s) {
+					px++
+					sx++
+					continue
+				}
+
+			case '[':
+				if sx < len(s) {
+					matched, rest := matchClass(pattern[px+1:], s[sx])
+					if matched {
+						px = len(pattern) - len(rest)
+						sx++
+						continue
+					}
+				}
+
+			default:
+				if c == '\\' && px+1 < len(pattern) {
+					px++
+					c = pattern[px]
+				}
+				if sx < len(s) && c == s[sx] {
+					px++
+					sx++
+					continue
+				}
+			}
+		}
+		if star < 0 || starS == len(s) {
+			return false
+		}
+		starS++
+		px, sx = star+1, starS
+	}
+	return true
+}
+
+// matchClass matches c against the class starting right after '[' and
+// returns the pattern following the closing ']'.
+func matchClass(pattern string, c byte) (bool, string) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			if pattern[1] == c {
+				matched = true
+			}
+			pattern = pattern[2:]
+		case len(pattern) >= 3 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			pattern = pattern[3:]
+		default:
+			if pattern[0] == c {
+				matched = true
+			}
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return matched != negate, pattern
+}
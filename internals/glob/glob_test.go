@@ -0,0 +1,53 @@
+package glob
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"*", "", true},
+		{"*", "anything/with/slashes", true},
+		{"news.*", "news.sport", true},
+		{"news.*", "weather.today", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+		{"*a*a", "aXaXa", true},
+		{"*.txt", "a.txt.bak", false},
+		{"a**b", "ab", true},
+		{"*[0-9]", "abc7", true},
+		{"a\\", "a\\", true},
+		{"", "", true},
+		{"", "a", false},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMatchBacktracksLinearly(t *testing.T) {
+	// Retrying every split for every star made this take minutes.
+	pattern := strings.Repeat("a*", 30) + "b"
+	if Match(pattern, strings.Repeat("a", 4096)) {
+		t.Fatal("pattern matched a string without a 'b'")
+	}
+}
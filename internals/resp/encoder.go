@@ -0,0 +1,125 @@
+package resp
+
+import (
+	"math"
+	"strconv"
+)
+
+// Marshal encodes v for a client speaking protocol version proto. RESP3
+// types are sent to protocol 2 clients as their closest RESP2 equivalent:
+// maps, sets and pushes as flat arrays, doubles, big numbers and verbatim
+// strings as bulk strings, booleans as 1 or 0 and nulls as null bulk
+// strings. Attributes have no RESP2 form and are dropped.
+func (v Value) Marshal(proto int) []byte {
+	return v.appendTo(nil, proto)
+}
+
+func (v Value) appendTo(buf []byte, proto int) []byte {
+	resp3 := proto >= 3
+	if resp3 && len(v.Attrs) > 0 {
+		buf = appendAggregate(buf, ATTRIBUTE, len(v.Attrs)/2, v.Attrs, proto)
+	}
+
+	switch v.Typ {
+	case "string":
+		buf = appendLine(buf, STRING, v.Str)
+	case "error":
+		buf = appendLine(buf, ERROR, v.Err)
+	case "integer":
+		buf = appendLine(buf, INTEGER, strconv.Itoa(v.Num))
+	case "bulk":
+		buf = appendBulk(buf, BULK, v.Bulk)
+	case "array":
+		buf = appendAggregate(buf, ARRAY, len(v.Array), v.Array, proto)
+	case "null":
+		if resp3 {
+			buf = append(buf, NULL, '\r', '\n')
+		} else {
+			buf = append(buf, "$-1\r\n"...)
+		}
+	case "boolean":
+		switch {
+		case resp3 && v.Bool:
+			buf = appendLine(buf, BOOLEAN, "t")
+		case resp3:
+			buf = appendLine(buf, BOOLEAN, "f")
+		case v.Bool:
+			buf = appendLine(buf, INTEGER, "1")
+		default:
+			buf = appendLine(buf, INTEGER, "0")
+		}
+	case "double":
+		if resp3 {
+			buf = appendLine(buf, DOUBLE, FormatDouble(v.Double))
+		} else {
+			buf = appendBulk(buf, BULK, FormatDouble(v.Double))
+		}
+	case "bignumber":
+		if resp3 {
+			buf = appendLine(buf, BIGNUMBER, v.Str)
+		} else {
+			buf = appendBulk(buf, BULK, v.Str)
+		}
+	case "verbatim":
+		if resp3 {
+			buf = appendBulk(buf, VERBATIM, v.Format+":"+v.Str)
+		} else {
+			buf = appendBulk(buf, BULK, v.Str)
+		}
+	case "map":
+		if resp3 {
+			buf = appendAggregate(buf, MAP, len(v.Array)/2, v.Array, proto)
+		} else {
+			buf = appendAggregate(buf, ARRAY, len(v.Array), v.Array, proto)
+		}
+	case "set":
+		buf = appendAggregate(buf, aggregateType(SET, resp3), len(v.Array), v.Array, proto)
+	case "push":
+		buf = appendAggregate(buf, aggregateType(PUSH, resp3), len(v.Array), v.Array, proto)
+	}
+	return buf
+}
+
+// FormatDouble renders f the way Redis does: the shortest representation
+// that parses back to f, with infinities spelled inf and -inf.
+func FormatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// aggregateType returns typ for RESP3 clients and the array type for RESP2
+// clients.
+func aggregateType(typ byte, resp3 bool) byte {
+	if resp3 {
+		return typ
+	}
+	return ARRAY
+}
+
+func appendLine(buf []byte, typ byte, s string) []byte {
+	buf = append(buf, typ)
+	buf = append(buf, s...)
+	return append(buf, '\r', '\n')
+}
+
+func appendBulk(buf []byte, typ byte, s string) []byte {
+	buf = appendLine(buf, typ, strconv.Itoa(len(s)))
+	buf = append(buf, s...)
+	return append(buf, '\r', '\n')
+}
+
+// appendAggregate encodes a header announcing n entries followed by items.
+func appendAggregate(buf []byte, typ byte, n int, items []Value, proto int) []byte {
+	buf = appendLine(buf, typ, strconv.Itoa(n))
+	for _, item := range items {
+		buf = item.appendTo(buf, proto)
+	}
+	return buf
+}
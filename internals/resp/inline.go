@@ -0,0 +1,148 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnbalancedQuotes = errors.New("unbalanced quotes in request")
+	ErrInlineTooBig     = errors.New("too big inline request")
+)
+
+// maxInline bounds the length of an inline command, so a client that never
+// sends a newline cannot grow the server's memory without limit.
+const maxInline = 64 << 10
+
+// ReadCommand reads the next command sent by a client. Besides RESP arrays
+// it accepts the inline format typed into telnet or nc, a line of space
+// separated arguments, which is returned as an array of bulk strings. A
+// blank line comes back as an empty array. As in Redis, anything not
+// starting with '*' is read as an inline command.
+func (resp *Resp) ReadCommand() (Value, error) {
+	b, err := resp.r.Peek(1)
+	if err != nil {
+		return Value{}, err
+	}
+	if b[0] == ARRAY {
+		return resp.ReadValue()
+	}
+
+	line, err := resp.readInline()
+	if err != nil {
+		return Value{}, err
+	}
+	args, err := splitInline(strings.TrimSuffix(line, "\r"))
+	if err != nil {
+		return Value{}, err
+	}
+	v := Value{Typ: "array", Array: make([]Value, len(args))}
+	for i, arg := range args {
+		v.Array[i] = Value{Typ: "bulk", Bulk: arg}
+	}
+	return v, nil
+}
+
+// readInline reads a line up to maxInline bytes long, without its '\n'.
+func (resp *Resp) readInline() (string, error) {
+	var line []byte
+	for {
+		chunk, err := resp.r.ReadSlice('\n')
+		if len(line)+len(chunk) > maxInline+1 {
+			return "", ErrInlineTooBig
+		}
+		line = append(line, chunk...)
+		switch err {
+		case nil:
+			return string(line[:len(line)-1]), nil
+		case bufio.ErrBufferFull:
+		default:
+			return "", err
+		}
+	}
+}
+
+// splitInline splits an inline command into arguments the way redis-cli
+// does. "Double quoted" arguments understand backslash escapes such as \n
+// and \x41, 'single quoted' ones only \'. A closing quote must end the
+// argument.
+func splitInline(line string) ([]string, error) {
+	var args []string
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var arg []byte
+		for i < len(line) && !isSpace(line[i]) {
+			quote := line[i]
+			if quote != '"' && quote != '\'' {
+				arg = append(arg, quote)
+				i++
+				continue
+			}
+
+			for i++; ; i++ {
+				if i == len(line) {
+					return nil, ErrUnbalancedQuotes
+				}
+				c := line[i]
+				if c == quote {
+					break
+				}
+				if c == '\\' && i+1 < len(line) {
+					switch next := line[i+1]; {
+					case quote == '\'':
+						if next == '\'' {
+							c = next
+							i++
+						}
+					case next == 'x' && i+3 < len(line) && isHex(line[i+2]) && isHex(line[i+3]):
+						n, _ := strconv.ParseUint(line[i+2:i+4], 16, 8)
+						c = byte(n)
+						i += 3
+					default:
+						c = unescape(next)
+						i++
+					}
+				}
+				arg = append(arg, c)
+			}
+			i++
+			if i < len(line) && !isSpace(line[i]) {
+				return nil, ErrUnbalancedQuotes
+			}
+		}
+		args = append(args, string(arg))
+	}
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'a':
+		return '\a'
+	}
+	return c
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\v' || c == '\f'
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -15,6 +16,29 @@ const (
 	INTEGER = ':'
 	BULK    = '$'
 	ERROR   = '-'
+
+	// RESP3 types, only sent to clients that negotiated protocol 3 with
+	// HELLO.
+	NULL      = '_'
+	BOOLEAN   = '#'
+	DOUBLE    = ','
+	BIGNUMBER = '('
+	BULKERROR = '!'
+	VERBATIM  = '='
+	MAP       = '%'
+	SET       = '~'
+	ATTRIBUTE = '|'
+	PUSH      = '>'
+)
+
+// maxAggregate bounds the number of values in an array, map, set or push,
+// and maxBulk the length of a bulk string, as Redis' proto-max-bulk-len
+// does. Neither is allocated up front past maxPrealloc values, so a length
+// alone cannot make the server reserve memory the client never sends.
+const (
+	maxAggregate = 1<<31 - 1
+	maxBulk      = 512 << 20
+	maxPrealloc  = 1024
 )
 
 var (
@@ -30,13 +54,23 @@ func NewResp(r *bufio.Reader) *Resp {
 	return &Resp{r: r}
 }
 
+// Value is a single RESP value. Maps and attributes keep their keys and
+// values alternating in Array, sets and pushes their elements. Big numbers
+// keep their digits in Str, verbatim strings their text in Str and its
+// three letter format, e.g. "txt", in Format.
 type Value struct {
-	Typ   string
-	Str   string
-	Array []Value
-	Num   int
-	Bulk  string
-	Err   string
+	Typ    string
+	Str    string
+	Array  []Value
+	Num    int
+	Bulk   string
+	Err    string
+	Double float64
+	Bool   bool
+	Format string
+	// Attrs holds the attributes sent ahead of the value, as alternating
+	// keys and values.
+	Attrs []Value
 }
 
 func (resp *Resp) ReadValue() (Value, error) {
@@ -63,11 +97,57 @@ func (resp *Resp) ReadValue() (Value, error) {
 	case ERROR:
 		return resp.readError()
 
+	case NULL:
+		if _, err := resp.readEmptyLine(); err != nil {
+			return Value{}, err
+		}
+		return Value{Typ: "null"}, nil
+
+	case BOOLEAN:
+		return resp.readBoolean()
+
+	case DOUBLE:
+		return resp.readDouble()
+
+	case BIGNUMBER:
+		return resp.readBigNumber()
+
+	case BULKERROR:
+		v, err := resp.readBulk()
+		return Value{Typ: "error", Err: v.Bulk}, err
+
+	case VERBATIM:
+		return resp.readVerbatim()
+
+	case MAP:
+		return resp.readAggregate("map", 2)
+
+	case SET:
+		return resp.readAggregate("set", 1)
+
+	case PUSH:
+		return resp.readAggregate("push", 1)
+
+	case ATTRIBUTE:
+		attrs, err := resp.readAggregate("attribute", 2)
+		if err != nil {
+			return Value{}, err
+		}
+		v, err := resp.ReadValue()
+		v.Attrs = attrs.Array
+		return v, err
+
 	default:
 		return Value{}, ErrUnexpectedType
 	}
 }
 
+// Buffered returns the number of bytes read from the connection but not
+// yet parsed, which is non-zero when a client pipelines commands.
+func (resp *Resp) Buffered() int {
+	return resp.r.Buffered()
+}
+
 func (resp *Resp) readLine() ([]byte, int, error) {
 	var line []byte
 	var n int
@@ -111,8 +191,11 @@ func (resp *Resp) readArray() (Value, error) {
 	if length < 0 {
 		return Value{Typ: "null"}, nil
 	}
+	if length > maxAggregate {
+		return v, fmt.Errorf("%w: invalid array length", ErrInvalidSyntax)
+	}
 
-	v.Array = make([]Value, length)
+	v.Array = make([]Value, 0, min(length, maxPrealloc))
 
 	for i := 0; i < length; i++ {
 		val, err := resp.ReadValue()
@@ -121,7 +204,7 @@ func (resp *Resp) readArray() (Value, error) {
 			return v, err
 		}
 		// log.Printf("Array element %d: %+v", i, val)
-		v.Array[i] = val
+		v.Array = append(v.Array, val)
 	}
 	return v, nil
 }
@@ -135,6 +218,9 @@ func (resp *Resp) readBulk() (Value, error) {
 	if length < 0 {
 		return Value{Typ: "null"}, nil
 	}
+	if length > maxBulk {
+		return v, fmt.Errorf("%w: invalid bulk length", ErrInvalidSyntax)
+	}
 	data := make([]byte, length)
 	if _, err := io.ReadFull(resp.r, data); err != nil {
 		return v, fmt.Errorf("%w: failed to read bulk data: %v", ErrInvalidSyntax, err)
@@ -186,3 +272,87 @@ func (resp *Resp) readError() (Value, error) {
 	v.Err = string(str)
 	return v, nil
 }
+
+// readEmptyLine reads the CRLF ending a value that has no payload.
+func (resp *Resp) readEmptyLine() (Value, error) {
+	line, _, err := resp.readLine()
+	if err != nil {
+		return Value{}, err
+	}
+	if len(line) != 0 {
+		return Value{}, fmt.Errorf("%w: unexpected payload %q", ErrInvalidSyntax, line)
+	}
+	return Value{}, nil
+}
+
+func (resp *Resp) readBoolean() (Value, error) {
+	line, _, err := resp.readLine()
+	if err != nil {
+		return Value{}, err
+	}
+	switch string(line) {
+	case "t":
+		return Value{Typ: "boolean", Bool: true}, nil
+	case "f":
+		return Value{Typ: "boolean", Bool: false}, nil
+	}
+	return Value{}, fmt.Errorf("%w: invalid boolean %q", ErrInvalidSyntax, line)
+}
+
+func (resp *Resp) readDouble() (Value, error) {
+	line, _, err := resp.readLine()
+	if err != nil {
+		return Value{}, err
+	}
+	f, err := strconv.ParseFloat(string(line), 64)
+	if err != nil {
+		return Value{}, fmt.Errorf("%w: invalid double %q", ErrInvalidSyntax, line)
+	}
+	return Value{Typ: "double", Double: f}, nil
+}
+
+func (resp *Resp) readBigNumber() (Value, error) {
+	line, _, err := resp.readLine()
+	if err != nil {
+		return Value{}, err
+	}
+	digits := strings.TrimPrefix(string(line), "-")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Value{}, fmt.Errorf("%w: invalid big number %q", ErrInvalidSyntax, line)
+	}
+	return Value{Typ: "bignumber", Str: string(line)}, nil
+}
+
+func (resp *Resp) readVerbatim() (Value, error) {
+	v, err := resp.readBulk()
+	if err != nil {
+		return v, err
+	}
+	if len(v.Bulk) < 4 || v.Bulk[3] != ':' {
+		return Value{}, fmt.Errorf("%w: verbatim string without format", ErrInvalidSyntax)
+	}
+	return Value{Typ: "verbatim", Format: v.Bulk[:3], Str: v.Bulk[4:]}, nil
+}
+
+// readAggregate reads a map, set, push or attribute of n entries, each made
+// of per values.
+func (resp *Resp) readAggregate(typ string, per int) (Value, error) {
+	v := Value{Typ: typ}
+	length, _, err := resp.readInt()
+	if err != nil {
+		return v, err
+	}
+	if length < 0 || length > maxAggregate/per {
+		return v, fmt.Errorf("%w: invalid %s length", ErrInvalidSyntax, typ)
+	}
+
+	v.Array = make([]Value, 0, min(length*per, maxPrealloc))
+	for i := 0; i < length*per; i++ {
+		val, err := resp.ReadValue()
+		if err != nil {
+			return v, err
+		}
+		v.Array = append(v.Array, val)
+	}
+	return v, nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -140,6 +141,70 @@ func TestRespReadValue(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name:     "RESP3 Null",
+			input:    []byte("_\r\n"),
+			expected: Value{Typ: "null"},
+		},
+		{
+			name:     "RESP3 Boolean",
+			input:    []byte("#t\r\n"),
+			expected: Value{Typ: "boolean", Bool: true},
+		},
+		{
+			name:     "RESP3 Double",
+			input:    []byte(",-1.5e3\r\n"),
+			expected: Value{Typ: "double", Double: -1500},
+		},
+		{
+			name:     "RESP3 Big Number",
+			input:    []byte("(3492890328409238509324850943850943825024385\r\n"),
+			expected: Value{Typ: "bignumber", Str: "3492890328409238509324850943850943825024385"},
+		},
+		{
+			name:     "RESP3 Bulk Error",
+			input:    []byte("!21\r\nSYNTAX invalid syntax\r\n"),
+			expected: Value{Typ: "error", Err: "SYNTAX invalid syntax"},
+		},
+		{
+			name:     "RESP3 Verbatim String",
+			input:    []byte("=15\r\ntxt:Some string\r\n"),
+			expected: Value{Typ: "verbatim", Format: "txt", Str: "Some string"},
+		},
+		{
+			name:  "RESP3 Map",
+			input: []byte("%2\r\n+first\r\n:1\r\n+second\r\n:2\r\n"),
+			expected: Value{Typ: "map", Array: []Value{
+				{Typ: "string", Str: "first"}, {Typ: "integer", Num: 1},
+				{Typ: "string", Str: "second"}, {Typ: "integer", Num: 2},
+			}},
+		},
+		{
+			name:  "RESP3 Set",
+			input: []byte("~2\r\n+a\r\n+b\r\n"),
+			expected: Value{Typ: "set", Array: []Value{
+				{Typ: "string", Str: "a"}, {Typ: "string", Str: "b"},
+			}},
+		},
+		{
+			name:  "RESP3 Push",
+			input: []byte(">2\r\n+message\r\n$2\r\nhi\r\n"),
+			expected: Value{Typ: "push", Array: []Value{
+				{Typ: "string", Str: "message"}, {Typ: "bulk", Bulk: "hi"},
+			}},
+		},
+		{
+			name:  "RESP3 Attribute",
+			input: []byte("|1\r\n+ttl\r\n:3600\r\n:42\r\n"),
+			expected: Value{Typ: "integer", Num: 42, Attrs: []Value{
+				{Typ: "string", Str: "ttl"}, {Typ: "integer", Num: 3600},
+			}},
+		},
+		{
+			name:    "RESP3 Invalid Boolean",
+			input:   []byte("#x\r\n"),
+			wantErr: ErrInvalidSyntax,
+		},
 		{
 			name:     "Invalid Type",
 			input:    []byte("?invalid\r\n"),
@@ -158,6 +223,43 @@ func TestRespReadValue(t *testing.T) {
 			expected: Value{},
 			wantErr:  io.EOF,
 		},
+		{
+			name:    "Overflowing Map Length",
+			input:   []byte("*1\r\n%4611686018427387904\r\n"),
+			wantErr: ErrInvalidSyntax,
+		},
+		{
+			name:    "Overflowing Attribute Length",
+			input:   []byte("|4611686018427387904\r\n"),
+			wantErr: ErrInvalidSyntax,
+		},
+		{
+			name:    "Huge Set Length",
+			input:   []byte("~9223372036854775807\r\n"),
+			wantErr: ErrInvalidSyntax,
+		},
+		{
+			name:    "Negative Push Length",
+			input:   []byte(">-1\r\n"),
+			wantErr: ErrInvalidSyntax,
+		},
+		{
+			name:    "Huge Array Length",
+			input:   []byte("*9223372036854775807\r\n"),
+			wantErr: ErrInvalidSyntax,
+		},
+		{
+			name:    "Huge Bulk Length",
+			input:   []byte("$9223372036854775807\r\n"),
+			wantErr: ErrInvalidSyntax,
+		},
+		{
+			// A length within bounds is not allocated before its values
+			// arrive.
+			name:    "Largest Array Length",
+			input:   []byte("*2147483647\r\n:1\r\n"),
+			wantErr: io.EOF,
+		},
 		{
 			name:     "Missing CRLF in BULK String",
 			input:    []byte("$5\r\nhello"),
@@ -208,3 +310,134 @@ func TestRespReadValue(t *testing.T) {
 		})
 	}
 }
+
+func TestMarshal(t *testing.T) {
+	v := Value{Typ: "map", Array: []Value{
+		{Typ: "bulk", Bulk: "score"},
+		{Typ: "double", Double: 1.5},
+		{Typ: "bulk", Bulk: "members"},
+		{Typ: "set", Array: []Value{{Typ: "bulk", Bulk: "a"}}},
+		{Typ: "bulk", Bulk: "ok"},
+		{Typ: "boolean", Bool: true},
+		{Typ: "bulk", Bulk: "missing"},
+		{Typ: "null"},
+	}}
+
+	tests := []struct {
+		proto int
+		want  string
+	}{
+		{2, "*8\r\n$5\r\nscore\r\n$3\r\n1.5\r\n$7\r\nmembers\r\n*1\r\n$1\r\na\r\n$2\r\nok\r\n:1\r\n$7\r\nmissing\r\n$-1\r\n"},
+		{3, "%4\r\n$5\r\nscore\r\n,1.5\r\n$7\r\nmembers\r\n~1\r\n$1\r\na\r\n$2\r\nok\r\n#t\r\n$7\r\nmissing\r\n_\r\n"},
+	}
+	for _, tt := range tests {
+		if got := string(v.Marshal(tt.proto)); got != tt.want {
+			t.Errorf("RESP%d: expected %q, got %q", tt.proto, tt.want, got)
+		}
+	}
+
+	got, err := NewResp(bufio.NewReader(bytes.NewReader(v.Marshal(3)))).ReadValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("RESP3 round trip: expected %+v, got %+v", v, got)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+
+	w.WriteSimpleString("OK")
+	w.WriteInteger(-3)
+	w.WriteBulkStrings([]string{"a", ""})
+	w.WriteNull()
+	w.WriteNullArray()
+	w.WriteMapLen(1)
+	w.WriteBulkString("k")
+	w.WriteDouble(2.5)
+	if out.Len() != 0 {
+		t.Fatalf("replies were written before Flush: %q", out.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "+OK\r\n:-3\r\n*2\r\n$1\r\na\r\n$0\r\n\r\n$-1\r\n*-1\r\n*2\r\n$1\r\nk\r\n$3\r\n2.5\r\n"
+	if out.String() != want {
+		t.Errorf("RESP2: expected %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	w.SetProtocol(3)
+	w.WriteNull()
+	w.WriteNullArray()
+	w.WriteMapLen(1)
+	w.WriteBulkString("k")
+	w.WriteDouble(2.5)
+	w.WriteSetLen(0)
+	w.WriteVerbatim("txt", "hi")
+	w.WriteError("ERR oops")
+	w.Flush()
+	want = "_\r\n_\r\n%1\r\n$1\r\nk\r\n,2.5\r\n~0\r\n=6\r\ntxt:hi\r\n-ERR oops\r\n"
+	if out.String() != want {
+		t.Errorf("RESP3: expected %q, got %q", want, out.String())
+	}
+}
+
+func TestReadCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		wantErr  error
+	}{
+		{name: "Inline", input: "SET key  value\r\n", expected: []string{"SET", "key", "value"}},
+		{name: "Bare Newline", input: "PING\n", expected: []string{"PING"}},
+		{name: "Blank Line", input: "  \r\nPING\r\n", expected: nil},
+		{name: "Double Quotes", input: `SET k "a b\n\x41\"" ""` + "\r\n", expected: []string{"SET", "k", "a b\nA\"", ""}},
+		{name: "Single Quotes", input: `SET k 'it\'s \n'` + "\r\n", expected: []string{"SET", "k", `it's \n`}},
+		{name: "Quote Inside Word", input: "SET k foo\"bar\"\r\n", expected: []string{"SET", "k", "foobar"}},
+		{name: "RESP Array", input: "*1\r\n$4\r\nPING\r\n", expected: []string{"PING"}},
+		{name: "Type Byte Starts Inline", input: "+PING $1\r\n", expected: []string{"+PING", "$1"}},
+		{name: "Longest Inline", input: strings.Repeat("a", maxInline) + "\n", expected: []string{strings.Repeat("a", maxInline)}},
+		{name: "Too Big Inline", input: strings.Repeat("a", maxInline+1) + "\n", wantErr: ErrInlineTooBig},
+		{name: "Too Big Without Newline", input: strings.Repeat("a", 2*maxInline), wantErr: ErrInlineTooBig},
+		{name: "Unbalanced Quotes", input: "SET k \"abc\r\n", wantErr: ErrUnbalancedQuotes},
+		{name: "Text After Closing Quote", input: "SET k \"a\"b\r\n", wantErr: ErrUnbalancedQuotes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := NewResp(bufio.NewReader(bytes.NewReader([]byte(tt.input)))).ReadCommand()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var args []string
+			for _, v := range val.Array {
+				args = append(args, v.Bulk)
+			}
+			if !reflect.DeepEqual(args, tt.expected) {
+				t.Errorf("expected: %q, got: %q", tt.expected, args)
+			}
+		})
+	}
+}
+
+func TestWriterDropsLargeScratch(t *testing.T) {
+	w := NewWriter(io.Discard)
+	w.WriteValue(Value{Typ: "array", Array: []Value{{Typ: "bulk", Bulk: string(make([]byte, 2*maxScratch))}}})
+	if cap(w.buf) > maxScratch {
+		t.Fatalf("kept a %d byte scratch buffer after a large reply", cap(w.buf))
+	}
+	w.WriteInteger(1)
+	if cap(w.buf) == 0 {
+		t.Fatal("small replies do not reuse the scratch buffer")
+	}
+}
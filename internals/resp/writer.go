@@ -0,0 +1,139 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// Writer encodes replies into a buffer that is sent by Flush, so replies to
+// a batch of pipelined commands reach the client in as few writes as
+// possible. RESP3 types are encoded for the protocol version set with
+// SetProtocol, RESP2 unless changed.
+//
+// Write errors are sticky and reported by Flush.
+type Writer struct {
+	w     *bufio.Writer
+	proto int
+	// buf is scratch space replies are encoded into.
+	buf []byte
+}
+
+// maxScratch bounds the scratch buffer kept between replies, so one huge
+// reply does not pin its memory for the life of the connection.
+const maxScratch = 64 << 10
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w), proto: 2}
+}
+
+// Protocol returns the RESP version replies are encoded for.
+func (w *Writer) Protocol() int {
+	return w.proto
+}
+
+func (w *Writer) SetProtocol(proto int) {
+	w.proto = proto
+}
+
+// Write adds already encoded bytes to the buffer.
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.w.Write(b)
+}
+
+// Flush sends everything buffered so far.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Buffered returns how many bytes are waiting for Flush.
+func (w *Writer) Buffered() int {
+	return w.w.Buffered()
+}
+
+func (w *Writer) WriteValue(v Value) {
+	w.emit(v.appendTo(w.buf[:0], w.proto))
+}
+
+func (w *Writer) WriteSimpleString(s string) {
+	w.emit(appendLine(w.buf[:0], STRING, s))
+}
+
+// WriteError writes an error reply. msg starts with the error code, e.g.
+// "ERR" or "WRONGTYPE".
+func (w *Writer) WriteError(msg string) {
+	w.emit(appendLine(w.buf[:0], ERROR, msg))
+}
+
+func (w *Writer) WriteInteger(n int) {
+	w.emit(appendLine(w.buf[:0], INTEGER, strconv.Itoa(n)))
+}
+
+func (w *Writer) WriteBulkString(s string) {
+	w.emit(appendLine(w.buf[:0], BULK, strconv.Itoa(len(s))))
+	w.w.WriteString(s)
+	w.w.WriteString("\r\n")
+}
+
+// WriteBulkStrings writes an array of bulk strings.
+func (w *Writer) WriteBulkStrings(items []string) {
+	w.WriteArrayLen(len(items))
+	for _, item := range items {
+		w.WriteBulkString(item)
+	}
+}
+
+// WriteNull writes a null, which RESP2 clients get as a null bulk string.
+func (w *Writer) WriteNull() {
+	w.WriteValue(Value{Typ: "null"})
+}
+
+// WriteNullArray writes a null, which RESP2 clients get as a null array.
+func (w *Writer) WriteNullArray() {
+	if w.proto >= 3 {
+		w.WriteNull()
+		return
+	}
+	w.emit(appendLine(w.buf[:0], ARRAY, "-1"))
+}
+
+// WriteArrayLen starts an array, the caller writes its n elements next.
+func (w *Writer) WriteArrayLen(n int) {
+	w.emit(appendLine(w.buf[:0], ARRAY, strconv.Itoa(n)))
+}
+
+// WriteMapLen starts a map of n entries, the caller writes each key followed
+// by its value next. RESP2 clients get a flat array of keys and values.
+func (w *Writer) WriteMapLen(n int) {
+	if w.proto >= 3 {
+		w.emit(appendLine(w.buf[:0], MAP, strconv.Itoa(n)))
+		return
+	}
+	w.WriteArrayLen(n * 2)
+}
+
+// WriteSetLen starts a set, an array for RESP2 clients.
+func (w *Writer) WriteSetLen(n int) {
+	w.emit(appendLine(w.buf[:0], aggregateType(SET, w.proto >= 3), strconv.Itoa(n)))
+}
+
+// WriteDouble writes a double, a bulk string for RESP2 clients.
+func (w *Writer) WriteDouble(f float64) {
+	w.WriteValue(Value{Typ: "double", Double: f})
+}
+
+// WriteVerbatim writes a verbatim string of the given three letter format,
+// a bulk string for RESP2 clients.
+func (w *Writer) WriteVerbatim(format, s string) {
+	w.WriteValue(Value{Typ: "verbatim", Format: format, Str: s})
+}
+
+// emit moves an encoded reply into the output buffer, keeping the scratch
+// buffer for the next one unless it grew past maxScratch.
+func (w *Writer) emit(b []byte) {
+	w.w.Write(b)
+	if cap(b) > maxScratch {
+		b = nil
+	}
+	w.buf = b[:0]
+}
@@ -0,0 +1,364 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go_redis/internals/store"
+)
+
+// File layout:
+//
+//	"VOLTKV" <version:uint16>
+//	[opSelectDB <db:uvarint>]
+//	[opExpiry <unix ms:int64>] <type:byte> <key> <value>   repeated
+//	opEOF <crc64 of everything before:uint64>
+//
+// Entries belong to database 0 until an opSelectDB switches to another one.
+//
+// Strings are a uvarint length followed by the raw bytes, lists, hashes and
+// sets a uvarint element count followed by their strings. Sorted sets store
+// each member followed by its score as a little-endian float64. Hashes with
+// field expiries are written as typeHashExpiries, each field followed by its
+// expiry in unix milliseconds as a uvarint, 0 meaning none.
+const (
+	magic   = "VOLTKV"
+	Version = 3
+
+	typeHashExpiries = 0x40
+
+	opExpiry   = 0xFC
+	opSelectDB = 0xFE
+	opEOF      = 0xFF
+
+	maxLength = 512 << 20
+)
+
+var (
+	ErrBadMagic    = errors.New("not a snapshot file")
+	ErrBadVersion  = errors.New("unsupported snapshot version")
+	ErrBadChecksum = errors.New("snapshot checksum mismatch")
+	ErrBadType     = errors.New("unknown snapshot entry type")
+	ErrCorrupt     = errors.New("corrupt snapshot")
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// Save writes entries to path atomically: the snapshot is written to a
+// temporary file in the same directory and renamed over path once synced.
+func Save(path string, entries []store.Entry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "temp-*.vkv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp, entries); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func write(w io.Writer, entries []store.Entry) error {
+	bw := bufio.NewWriter(w)
+	enc := &encoder{w: bw, crc: crc64.New(crcTable)}
+
+	enc.raw([]byte(magic))
+	enc.raw(binary.BigEndian.AppendUint16(nil, Version))
+
+	db := 0
+	for _, e := range entries {
+		if e.DB != db {
+			enc.byte(opSelectDB)
+			enc.length(e.DB)
+			db = e.DB
+		}
+		if !e.Expiry.IsZero() {
+			enc.byte(opExpiry)
+			enc.raw(binary.LittleEndian.AppendUint64(nil, uint64(e.Expiry.UnixMilli())))
+		}
+		if e.Type == store.TypeHash && len(e.HashExpiries) > 0 {
+			enc.byte(typeHashExpiries)
+		} else {
+			enc.byte(byte(e.Type))
+		}
+		enc.string(e.Key)
+
+		switch e.Type {
+		case store.TypeString:
+			enc.string(e.Str)
+		case store.TypeList:
+			enc.length(len(e.List))
+			for _, v := range e.List {
+				enc.string(v)
+			}
+		case store.TypeHash:
+			enc.length(len(e.Hash))
+			for f, v := range e.Hash {
+				enc.string(f)
+				enc.string(v)
+				if len(e.HashExpiries) > 0 {
+					var ms int64
+					if exp, ok := e.HashExpiries[f]; ok {
+						ms = exp.UnixMilli()
+					}
+					enc.uvarint(uint64(ms))
+				}
+			}
+		case store.TypeSet:
+			enc.length(len(e.Set))
+			for _, m := range e.Set {
+				enc.string(m)
+			}
+		case store.TypeZSet:
+			enc.length(len(e.ZSet))
+			for _, m := range e.ZSet {
+				enc.string(m.Member)
+				enc.raw(binary.LittleEndian.AppendUint64(nil, math.Float64bits(m.Score)))
+			}
+		default:
+			return fmt.Errorf("%w: %d", ErrBadType, e.Type)
+		}
+	}
+
+	enc.byte(opEOF)
+	if enc.err != nil {
+		return enc.err
+	}
+	if _, err := bw.Write(binary.LittleEndian.AppendUint64(nil, enc.crc.Sum64())); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Load reads every entry from the snapshot at path. Nothing is returned
+// unless the whole file, checksum included, is valid.
+func Load(path string) ([]store.Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	dec := &decoder{r: br, crc: crc64.New(crcTable)}
+
+	if string(dec.raw(len(magic))) != magic {
+		if dec.err != nil {
+			return nil, dec.err
+		}
+		return nil, ErrBadMagic
+	}
+	version := binary.BigEndian.Uint16(dec.raw(2))
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	if version > Version {
+		return nil, fmt.Errorf("%w: %d", ErrBadVersion, version)
+	}
+
+	var entries []store.Entry
+	db := 0
+	for {
+		e := store.Entry{DB: db}
+
+		op := dec.byte()
+		if dec.err != nil {
+			return nil, dec.err
+		}
+		if op == opEOF {
+			break
+		}
+		if op == opSelectDB {
+			db = dec.length()
+			continue
+		}
+		if op == opExpiry {
+			ms := binary.LittleEndian.Uint64(dec.raw(8))
+			e.Expiry = time.UnixMilli(int64(ms))
+			op = dec.byte()
+		}
+
+		withExpiries := op == typeHashExpiries
+		if withExpiries {
+			op = byte(store.TypeHash)
+		}
+		e.Type = store.Type(op)
+		e.Key = dec.string()
+
+		switch e.Type {
+		case store.TypeString:
+			e.Str = dec.string()
+		case store.TypeList:
+			n := dec.length()
+			e.List = make([]string, 0, min(n, 1024))
+			for i := 0; i < n && dec.err == nil; i++ {
+				e.List = append(e.List, dec.string())
+			}
+		case store.TypeHash:
+			n := dec.length()
+			e.Hash = make(map[string]string, min(n, 1024))
+			for i := 0; i < n && dec.err == nil; i++ {
+				field := dec.string()
+				e.Hash[field] = dec.string()
+				if !withExpiries {
+					continue
+				}
+				if ms := dec.uvarint(); ms > 0 {
+					if e.HashExpiries == nil {
+						e.HashExpiries = make(map[string]time.Time)
+					}
+					e.HashExpiries[field] = time.UnixMilli(int64(ms))
+				}
+			}
+		case store.TypeSet:
+			n := dec.length()
+			e.Set = make([]string, 0, min(n, 1024))
+			for i := 0; i < n && dec.err == nil; i++ {
+				e.Set = append(e.Set, dec.string())
+			}
+		case store.TypeZSet:
+			n := dec.length()
+			e.ZSet = make([]store.ZMember, 0, min(n, 1024))
+			for i := 0; i < n && dec.err == nil; i++ {
+				member := dec.string()
+				score := math.Float64frombits(binary.LittleEndian.Uint64(dec.raw(8)))
+				e.ZSet = append(e.ZSet, store.ZMember{Member: member, Score: score})
+			}
+		default:
+			return nil, fmt.Errorf("%w: %d", ErrBadType, op)
+		}
+
+		if dec.err != nil {
+			return nil, dec.err
+		}
+		entries = append(entries, e)
+	}
+
+	sum := dec.crc.Sum64()
+	var stored [8]byte
+	if _, err := io.ReadFull(br, stored[:]); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint64(stored[:]) != sum {
+		return nil, ErrBadChecksum
+	}
+	return entries, nil
+}
+
+type encoder struct {
+	w   io.Writer
+	crc hash.Hash64
+	err error
+}
+
+func (e *encoder) raw(b []byte) {
+	if e.err != nil {
+		return
+	}
+	e.crc.Write(b)
+	_, e.err = e.w.Write(b)
+}
+
+func (e *encoder) byte(b byte) {
+	e.raw([]byte{b})
+}
+
+func (e *encoder) uvarint(n uint64) {
+	e.raw(binary.AppendUvarint(nil, n))
+}
+
+func (e *encoder) length(n int) {
+	e.uvarint(uint64(n))
+}
+
+func (e *encoder) string(s string) {
+	e.length(len(s))
+	e.raw([]byte(s))
+}
+
+type decoder struct {
+	r   *bufio.Reader
+	crc hash.Hash64
+	err error
+}
+
+func (d *decoder) raw(n int) []byte {
+	buf := make([]byte, n)
+	if d.err != nil {
+		return buf
+	}
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		d.err = unexpected(err)
+		return buf
+	}
+	d.crc.Write(buf)
+	return buf
+}
+
+func (d *decoder) byte() byte {
+	return d.raw(1)[0]
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	n, err := binary.ReadUvarint(checksumReader{d})
+	if err != nil {
+		d.err = unexpected(err)
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) length() int {
+	n := d.uvarint()
+	if n > maxLength {
+		d.err = fmt.Errorf("%w: length %d", ErrCorrupt, n)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	n := d.length()
+	return string(d.raw(n))
+}
+
+// checksumReader feeds bytes consumed by binary.ReadUvarint into the checksum.
+type checksumReader struct {
+	d *decoder
+}
+
+func (b checksumReader) ReadByte() (byte, error) {
+	c, err := b.d.r.ReadByte()
+	if err == nil {
+		b.d.crc.Write([]byte{c})
+	}
+	return c, err
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"go_redis/internals/store"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.vkv")
+	expiry := time.UnixMilli(time.Now().Add(time.Hour).UnixMilli())
+
+	want := []store.Entry{
+		{Key: "str", Type: store.TypeString, Str: "hello\r\nworld"},
+		{Key: "empty", Type: store.TypeString, Str: ""},
+		{Key: "ttl", Type: store.TypeString, Str: "v", Expiry: expiry},
+		{Key: "list", Type: store.TypeList, List: []string{"a", "b", "c"}},
+		{Key: "hash", Type: store.TypeHash, Hash: map[string]string{"f1": "v1", "f2": ""}},
+		{Key: "fieldttl", Type: store.TypeHash, Hash: map[string]string{"f1": "v1", "f2": "v2"}, HashExpiries: map[string]time.Time{"f2": expiry}},
+		{Key: "set", Type: store.TypeSet, Set: []string{"a", "b"}},
+		{Key: "zset", Type: store.TypeZSet, ZSet: []store.ZMember{{Member: "a", Score: -1.5}, {Member: "b", Score: 3}}},
+		{DB: 3, Key: "db3", Type: store.TypeString, Str: "three", Expiry: expiry},
+		{DB: 15, Key: "db15", Type: store.TypeList, List: []string{"x"}},
+	}
+
+	if err := Save(path, want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	sort.Slice(got, func(i, j int) bool { return got[i].Key < got[j].Key })
+	sort.Slice(want, func(i, j int) bool { return want[i].Key < want[j].Key })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestSnapshotChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.vkv")
+
+	if err := Save(path, []store.Entry{{Key: "k", Type: store.TypeString, Str: "value"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-10] ^= 0xFF
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); !errors.Is(err, ErrBadChecksum) {
+		t.Fatalf("expected %v, got %v", ErrBadChecksum, err)
+	}
+}
+
+func TestSnapshotBadMagic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.vkv")
+	if err := os.WriteFile(path, []byte("REDIS0011"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); !errors.Is(err, ErrBadMagic) {
+		t.Fatalf("expected %v, got %v", ErrBadMagic, err)
+	}
+}
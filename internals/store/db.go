@@ -0,0 +1,118 @@
+package store
+
+import "time"
+
+// DefaultDatabases is how many databases a server has unless configured
+// otherwise.
+const DefaultDatabases = 16
+
+// Databases are the numbered databases of a server, each an independent
+// keyspace. Clients pick the one their commands run against with SELECT.
+type Databases []*Store
+
+func NewDatabases(n int) Databases {
+	dbs := make(Databases, n)
+	for i := range dbs {
+		dbs[i] = NewStore()
+	}
+	return dbs
+}
+
+// Dirty sums the dirty counters of every database.
+func (dbs Databases) Dirty() uint64 {
+	var dirty uint64
+	for _, s := range dbs {
+		dirty += s.Dirty()
+	}
+	return dirty
+}
+
+// Dump returns a copy of every live key in every database, each entry
+// tagged with the index of its database.
+func (dbs Databases) Dump() []Entry {
+	var entries []Entry
+	for i, s := range dbs {
+		for _, e := range s.Dump() {
+			e.DB = i
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Swap exchanges the keys of databases i and j. Watches and blocked clients
+// belong to the database index rather than its keys, so they stay where
+// they are: watched keys that exist on either side are invalidated, and
+// clients blocked on a key that now holds a list are woken up.
+func (dbs Databases) Swap(i, j int) {
+	if i == j {
+		return
+	}
+	a, b := dbs[i], dbs[j]
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range []*Store{a, b} {
+		for key, w := range s.watches {
+			_, inA := a.keys[key]
+			_, inB := b.keys[key]
+			if inA || inB {
+				w.version++
+			}
+		}
+	}
+
+	a.keys, b.keys = b.keys, a.keys
+	a.index, b.index = b.index, a.index
+	a.expiries, b.expiries = b.expiries, a.expiries
+	a.hashExpiries, b.hashExpiries = b.hashExpiries, a.hashExpiries
+	a.dirty++
+
+	for _, s := range []*Store{a, b} {
+		for key := range s.blocking {
+			if v := s.lookup(key); v != nil && v.typ == TypeList {
+				s.signalReady(key)
+			}
+		}
+	}
+}
+
+// ExpireStats sums the expire statistics of every database. StalePercent is
+// averaged, weighted by how many keys with an expiry each database holds.
+func (dbs Databases) ExpireStats() ExpireStats {
+	var total ExpireStats
+	var weight float64
+	for _, s := range dbs {
+		s.mu.RLock()
+		stats, n := s.expireStats, float64(s.expiries.len())
+		s.mu.RUnlock()
+
+		total.ExpiredKeys += stats.ExpiredKeys
+		total.ExpiredFields += stats.ExpiredFields
+		total.TimeCapReached += stats.TimeCapReached
+		total.CycleTime += stats.CycleTime
+		total.StalePercent += stats.StalePercent * n
+		weight += n
+	}
+	if weight > 0 {
+		total.StalePercent /= weight
+	}
+	return total
+}
+
+// StartCleaner runs an active expire cycle over every database each
+// interval, so keys that are never accessed again are still deleted once
+// they expire. The time budget of a cycle is shared between the databases.
+func (dbs Databases) StartCleaner(interval time.Duration) {
+	budget := interval / 4 / time.Duration(len(dbs))
+	go func() {
+		for {
+			time.Sleep(interval)
+			for _, s := range dbs {
+				s.activeExpireCycle(budget)
+			}
+		}
+	}()
+}
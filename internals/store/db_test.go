@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMoveBetweenDatabases(t *testing.T) {
+	dbs := NewDatabases(2)
+	at := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	dbs[0].Set("k", "v")
+	dbs[0].ExpireAt("k", at, ExpireAlways)
+
+	if !dbs[0].Move("k", dbs[1]) {
+		t.Fatal("Move failed")
+	}
+	if dbs[0].Exists("k") {
+		t.Fatal("key still exists in the source database")
+	}
+	if got, _ := dbs[1].ExpireTime("k"); !got.Equal(at) {
+		t.Fatalf("expiry is %v after Move, want %v", got, at)
+	}
+
+	dbs[0].Set("k", "other")
+	if dbs[0].Move("k", dbs[1]) {
+		t.Fatal("Move replaced a key that exists in the destination")
+	}
+}
+
+func TestSwapDatabases(t *testing.T) {
+	dbs := NewDatabases(2)
+	dbs[0].Set("a", "0")
+	dbs[1].Set("b", "1")
+	version := dbs[0].Watch("b")
+	dbs[0].BlockOn("l")
+	dbs[1].RPush("l", "x")
+	dbs[0].ReadyKeys()
+
+	dbs.Swap(0, 1)
+
+	if v, _, _ := dbs[0].Get("b"); v != "1" || dbs[0].Exists("a") {
+		t.Fatal("database 0 does not hold the keys of database 1")
+	}
+	if v, _, _ := dbs[1].Get("a"); v != "0" {
+		t.Fatal("database 1 does not hold the keys of database 0")
+	}
+	if dbs[0].WatchVersion("b") == version {
+		t.Fatal("a watched key that appeared in the database was not invalidated")
+	}
+	if ready := dbs[0].ReadyKeys(); len(ready) != 1 || ready[0] != "l" {
+		t.Fatalf("ready keys after Swap are %v, want [l]", ready)
+	}
+}
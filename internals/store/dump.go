@@ -0,0 +1,104 @@
+package store
+
+import (
+	"maps"
+	"time"
+)
+
+// Entry is a point-in-time copy of a single key, used to persist the store.
+type Entry struct {
+	// DB is the index of the database the key belongs to.
+	DB     int
+	Key    string
+	Type   Type
+	Str    string
+	List   []string
+	Hash   map[string]string
+	Set    []string
+	ZSet   []ZMember
+	Expiry time.Time
+	// HashExpiries holds the expiry of the hash fields that have one.
+	HashExpiries map[string]time.Time
+}
+
+// Dump returns a copy of every live key. Only the in-memory copy is made
+// under the lock, so callers can serialize the result without blocking
+// writers for the duration of the write to disk.
+func (s *Store) Dump() []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(s.keys))
+	for key := range s.keys {
+		v := s.lookup(key)
+		if v == nil {
+			continue
+		}
+
+		expiry, _ := s.expiries.get(key)
+		e := Entry{Key: key, Type: v.typ, Expiry: expiry}
+		switch v.typ {
+		case TypeString:
+			e.Str = v.stringValue()
+		case TypeList:
+			e.List = v.list.values()
+		case TypeHash:
+			e.Hash = make(map[string]string, len(v.hash))
+			v.eachField(func(f, val string) {
+				e.Hash[f] = val
+				if exp, hasExpiry := v.fieldExpiries[f]; hasExpiry {
+					if e.HashExpiries == nil {
+						e.HashExpiries = make(map[string]time.Time)
+					}
+					e.HashExpiries[f] = exp
+				}
+			})
+		case TypeSet:
+			e.Set = setMembers(v.set)
+		case TypeZSet:
+			e.ZSet = v.zset.rangeByRank(ZRangeSpec{Start: 0, Stop: -1})
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Restore loads an entry produced by Dump, replacing any existing value.
+func (s *Store) Restore(e Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := &value{typ: e.Type}
+	switch e.Type {
+	case TypeString:
+		v.str = e.Str
+	case TypeList:
+		v.list = newQuicklist(e.List...)
+	case TypeHash:
+		v.hash = e.Hash
+		v.index = newIndex(len(v.hash), maps.Keys(v.hash))
+		if len(e.HashExpiries) > 0 {
+			v.fieldExpiries = e.HashExpiries
+		}
+	case TypeSet:
+		v.set = make(map[string]struct{}, len(e.Set))
+		for _, m := range e.Set {
+			v.addMember(m)
+		}
+	case TypeZSet:
+		v.zset = newZSet()
+		for _, m := range e.ZSet {
+			v.zset.set(m.Member, m.Score)
+		}
+	}
+
+	s.add(e.Key, v)
+	s.expiries.delete(e.Key)
+	s.hashExpiries.delete(e.Key)
+	if v.fieldExpiries != nil {
+		s.hashExpiries.set(e.Key, earliest(v.fieldExpiries))
+	}
+	if !e.Expiry.IsZero() {
+		s.expiries.set(e.Key, e.Expiry)
+	}
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	// activeExpireSampleSize is how many keys with an expiry, and how many
+	// hashes with field expiries, are looked at per sample.
+	activeExpireSampleSize = 20
+	// activeExpireAcceptableStale is the percentage of expired keys in a
+	// sample below which a cycle stops, as the remaining work is not worth
+	// the time.
+	activeExpireAcceptableStale = 10
+)
+
+// ExpireStats describes how expired keys and hash fields have been removed.
+type ExpireStats struct {
+	// ExpiredKeys and ExpiredFields count keys and hash fields deleted
+	// because they expired, whether found by a command or the active cycle.
+	ExpiredKeys   uint64
+	ExpiredFields uint64
+	// StalePercent estimates the percentage of keys with an expiry that
+	// have expired but not yet been deleted, from recent samples.
+	StalePercent float64
+	// TimeCapReached counts the cycles stopped by their time budget rather
+	// than because few enough sampled keys had expired.
+	TimeCapReached uint64
+	// CycleTime is the total time spent in active expire cycles.
+	CycleTime time.Duration
+}
+
+func (s *Store) ExpireStats() ExpireStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.expireStats
+}
+
+// activeExpireCycle deletes expired keys by sampling random keys with an
+// expiry, repeating while a large share of the sample had expired and the
+// cycle has not used up budget. The lock is only held for one sample at a
+// time, so commands keep running between samples.
+func (s *Store) activeExpireCycle(budget time.Duration) {
+	start := time.Now()
+	for s.expireSample() {
+		if time.Since(start) > budget {
+			s.mu.Lock()
+			s.expireStats.TimeCapReached++
+			s.mu.Unlock()
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.expireStats.CycleTime += time.Since(start)
+	s.mu.Unlock()
+}
+
+// expireSample looks at up to activeExpireSampleSize random keys with an
+// expiry and as many hashes with field expiries, deleting whatever has
+// expired. It reports whether enough of either sample had expired that
+// another one is worth taking.
+func (s *Store) expireSample() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	keys := min(activeExpireSampleSize, s.expiries.len())
+	expiredKeys := 0
+	for i := 0; i < keys; i++ {
+		e := s.expiries.random(s.sampler)
+		if now.After(e.at) {
+			s.remove(e.key)
+			s.expireStats.ExpiredKeys++
+			expiredKeys++
+		}
+	}
+	if keys > 0 {
+		current := float64(expiredKeys) * 100 / float64(keys)
+		s.expireStats.StalePercent = s.expireStats.StalePercent*0.95 + current*0.05
+	}
+
+	hashes := min(activeExpireSampleSize, s.hashExpiries.len())
+	expiredHashes := 0
+	for i := 0; i < hashes; i++ {
+		e := s.hashExpiries.random(s.sampler)
+		if now.After(e.at) {
+			s.expireFields(e.key, s.keys[e.key], now)
+			expiredHashes++
+		}
+	}
+
+	return stale(expiredKeys, keys) || stale(expiredHashes, hashes)
+}
+
+func stale(expired, sampled int) bool {
+	return sampled > 0 && expired*100 > sampled*activeExpireAcceptableStale
+}
+
+// expiryTable maps keys to a time and also keeps them in a slice, so that a
+// key can be picked uniformly at random, which iterating a map doesn't do.
+type expiryTable struct {
+	index   map[string]int
+	entries []expiryEntry
+}
+
+type expiryEntry struct {
+	key string
+	at  time.Time
+}
+
+func newExpiryTable() *expiryTable {
+	return &expiryTable{index: make(map[string]int)}
+}
+
+func (t *expiryTable) len() int {
+	return len(t.entries)
+}
+
+func (t *expiryTable) get(key string) (time.Time, bool) {
+	i, ok := t.index[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	return t.entries[i].at, true
+}
+
+func (t *expiryTable) set(key string, at time.Time) {
+	if i, ok := t.index[key]; ok {
+		t.entries[i].at = at
+		return
+	}
+	t.index[key] = len(t.entries)
+	t.entries = append(t.entries, expiryEntry{key: key, at: at})
+}
+
+// delete removes key by moving the last entry into its slot.
+func (t *expiryTable) delete(key string) {
+	i, ok := t.index[key]
+	if !ok {
+		return
+	}
+	last := len(t.entries) - 1
+	if i != last {
+		t.entries[i] = t.entries[last]
+		t.index[t.entries[i].key] = i
+	}
+	t.entries[last] = expiryEntry{}
+	t.entries = t.entries[:last]
+	delete(t.index, key)
+}
+
+// random returns a random entry, the table must not be empty.
+func (t *expiryTable) random(r *rand.Rand) expiryEntry {
+	return t.entries[r.Intn(len(t.entries))]
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestActiveExpireCycle(t *testing.T) {
+	s := NewStore()
+	// With a fixed seed the samples, and so where the cycle stops, are the
+	// same on every run.
+	s.sampler = rand.New(rand.NewSource(1))
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Hour)
+	for i := 0; i < 10000; i++ {
+		key := strconv.Itoa(i)
+		s.Set(key, "v")
+		if i%10 == 0 {
+			s.expiries.set(key, future)
+		} else {
+			s.expiries.set(key, past)
+		}
+	}
+	s.HSet("h", map[string]string{"a": "1", "b": "2"})
+	s.HExpireAt("h", future, ExpireAlways, "a", "b")
+	s.keys["h"].fieldExpiries["a"] = past
+	s.hashExpiries.set("h", past)
+
+	s.activeExpireCycle(time.Minute)
+
+	stats := s.ExpireStats()
+	if stats.TimeCapReached != 0 {
+		t.Fatal("cycle ran out of time")
+	}
+	if stats.ExpiredKeys < 8000 {
+		t.Fatalf("cycle expired %d of 9000 keys", stats.ExpiredKeys)
+	}
+	for i := 0; i < 10000; i += 10 {
+		if !s.Exists(strconv.Itoa(i)) {
+			t.Fatalf("key %d without an expired TTL was deleted", i)
+		}
+	}
+	if stats.ExpiredFields != 1 || len(s.keys["h"].hash) != 1 {
+		t.Fatalf("cycle expired %d hash fields, want 1", stats.ExpiredFields)
+	}
+}
@@ -0,0 +1,505 @@
+package store
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrHashNotInteger = errors.New("hash value is not an integer")
+	ErrHashNotFloat   = errors.New("hash value is not a float")
+)
+
+// HSet sets the given fields and returns how many of them were new.
+func (s *Store) HSet(key string, fields map[string]string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeHash)
+	if err != nil {
+		return 0, err
+	}
+	if v == nil {
+		v = &value{typ: TypeHash, hash: make(map[string]string)}
+		s.add(key, v)
+	}
+	added := 0
+	for field, val := range fields {
+		if v.setField(field, val) {
+			added++
+		}
+		delete(v.fieldExpiries, field)
+	}
+	s.modified(key)
+	return added, nil
+}
+
+// HSetNX sets field only if it does not exist yet.
+func (s *Store) HSetNX(key, field, val string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeHash)
+	if err != nil {
+		return false, err
+	}
+	if v == nil {
+		v = &value{typ: TypeHash, hash: make(map[string]string)}
+		s.add(key, v)
+	} else if _, exists := v.hash[field]; exists {
+		return false, nil
+	}
+	v.setField(field, val)
+	s.modified(key)
+	return true, nil
+}
+
+func (s *Store) HGet(key, field string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeHash)
+	if err != nil || v == nil {
+		return "", false, err
+	}
+	if v.fieldExpired(field, time.Now()) {
+		return "", false, nil
+	}
+	val, ok := v.hash[field]
+	return val, ok, nil
+}
+
+// HMGet returns the values of fields, found reporting which of them exist.
+func (s *Store) HMGet(key string, fields ...string) (values []string, found []bool, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	values = make([]string, len(fields))
+	found = make([]bool, len(fields))
+	if v == nil {
+		return values, found, nil
+	}
+	now := time.Now()
+	for i, f := range fields {
+		if !v.fieldExpired(f, now) {
+			values[i], found[i] = v.hash[f]
+		}
+	}
+	return values, found, nil
+}
+
+func (s *Store) HGetAll(key string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeHash)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	copy := make(map[string]string, len(v.hash))
+	v.eachField(func(f, val string) {
+		copy[f] = val
+	})
+	return copy, nil
+}
+
+func (s *Store) HDel(key string, fields ...string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeHash)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	removed := 0
+	for _, f := range fields {
+		if _, exists := v.hash[f]; exists {
+			v.deleteField(f)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.removeIfEmpty(key, v)
+		s.modified(key)
+	}
+	return removed, nil
+}
+
+func (s *Store) HExists(key, field string) (bool, error) {
+	_, ok, err := s.HGet(key, field)
+	return ok, err
+}
+
+func (s *Store) HLen(key string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeHash)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	if len(v.fieldExpiries) == 0 {
+		return len(v.hash), nil
+	}
+	n := 0
+	v.eachField(func(string, string) {
+		n++
+	})
+	return n, nil
+}
+
+func (s *Store) HStrLen(key, field string) (int, error) {
+	val, _, err := s.HGet(key, field)
+	return len(val), err
+}
+
+func (s *Store) HKeys(key string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeHash)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(v.hash))
+	v.eachField(func(f, _ string) {
+		keys = append(keys, f)
+	})
+	return keys, nil
+}
+
+func (s *Store) HVals(key string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeHash)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	vals := make([]string, 0, len(v.hash))
+	v.eachField(func(_, val string) {
+		vals = append(vals, val)
+	})
+	return vals, nil
+}
+
+// HIncrBy adds delta to the integer stored in field, a missing field
+// counting as 0, and returns the new value.
+func (s *Store) HIncrBy(key, field string, delta int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeHash)
+	if err != nil {
+		return 0, err
+	}
+	var cur int64
+	if v != nil {
+		if val, exists := v.hash[field]; exists {
+			n, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || strconv.FormatInt(n, 10) != val {
+				return 0, ErrHashNotInteger
+			}
+			cur = n
+		}
+	}
+	if (delta > 0 && cur > math.MaxInt64-delta) || (delta < 0 && cur < math.MinInt64-delta) {
+		return 0, ErrOverflow
+	}
+
+	if v == nil {
+		v = &value{typ: TypeHash, hash: make(map[string]string)}
+		s.add(key, v)
+	}
+	v.setField(field, strconv.FormatInt(cur+delta, 10))
+	s.modified(key)
+	return cur + delta, nil
+}
+
+// HIncrByFloat adds incr to the number stored in field and returns the new
+// value as it is now stored.
+func (s *Store) HIncrByFloat(key, field string, incr float64) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeHash)
+	if err != nil {
+		return "", err
+	}
+	var cur float64
+	if v != nil {
+		if val, exists := v.hash[field]; exists {
+			cur, err = strconv.ParseFloat(val, 64)
+			if err != nil || math.IsNaN(cur) || math.IsInf(cur, 0) {
+				return "", ErrHashNotFloat
+			}
+		}
+	}
+	result := cur + incr
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return "", ErrNaNOrInfinity
+	}
+
+	if v == nil {
+		v = &value{typ: TypeHash, hash: make(map[string]string)}
+		s.add(key, v)
+	}
+	formatted := strconv.FormatFloat(result, 'f', -1, 64)
+	v.setField(field, formatted)
+	s.modified(key)
+	return formatted, nil
+}
+
+// HRandField returns up to count distinct random fields with their values,
+// or exactly -count fields that may repeat when count is negative.
+func (s *Store) HRandField(key string, count int) (fields, values []string, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeHash)
+	if err != nil || v == nil || count == 0 {
+		return nil, nil, err
+	}
+
+	// A count well below the size is drawn from the hash's index, skipping
+	// expired fields, so it costs O(count). Otherwise, or if too many of the
+	// fields drawn had expired, the live fields are copied.
+	ok := false
+	if v.index != nil && (count < 0 || count*3 <= len(v.hash)) {
+		now := time.Now()
+		n := count
+		if n < 0 {
+			n = -n
+		}
+		fields, ok = v.index.sample(n, count > 0, func(f string) bool {
+			return !v.fieldExpired(f, now)
+		})
+	}
+	if !ok {
+		all := make([]string, 0, len(v.hash))
+		v.eachField(func(f, _ string) {
+			all = append(all, f)
+		})
+		if count > 0 {
+			fields = pickRandom(all, min(count, len(all)))
+		} else {
+			for range -count {
+				fields = append(fields, all[rand.Intn(len(all))])
+			}
+		}
+	}
+
+	values = make([]string, len(fields))
+	for i, f := range fields {
+		values[i] = v.hash[f]
+	}
+	return fields, values, nil
+}
+
+// HExpireAt sets the expiry of each field to at when cond allows it. For
+// each field the result is -2 if it does not exist, 0 if cond prevented the
+// update, 1 if the expiry was set and 2 if at has passed and the field was
+// deleted.
+func (s *Store) HExpireAt(key string, at time.Time, cond ExpireCondition, fields ...string) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]int, len(fields))
+	now := time.Now()
+	changed := false
+	for i, f := range fields {
+		if v == nil {
+			result[i] = -2
+			continue
+		}
+		if _, exists := v.hash[f]; !exists {
+			result[i] = -2
+			continue
+		}
+		cur, hasExpiry := v.fieldExpiries[f]
+		if !cond.allows(cur, hasExpiry, at) {
+			continue
+		}
+		changed = true
+		if !at.After(now) {
+			v.deleteField(f)
+			result[i] = 2
+			continue
+		}
+		if v.fieldExpiries == nil {
+			v.fieldExpiries = make(map[string]time.Time)
+		}
+		v.fieldExpiries[f] = at
+		if cur, ok := s.hashExpiries.get(key); !ok || at.Before(cur) {
+			s.hashExpiries.set(key, at)
+		}
+		result[i] = 1
+	}
+
+	if changed {
+		s.removeIfEmpty(key, v)
+		s.modified(key)
+	}
+	return result, nil
+}
+
+// HExpireTime returns the expiry of each field in unix milliseconds, -1 if
+// the field has none and -2 if it does not exist.
+func (s *Store) HExpireTime(key string, fields ...string) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]int64, len(fields))
+	now := time.Now()
+	for i, f := range fields {
+		if v == nil || v.fieldExpired(f, now) {
+			result[i] = -2
+			continue
+		}
+		if _, exists := v.hash[f]; !exists {
+			result[i] = -2
+			continue
+		}
+		if exp, hasExpiry := v.fieldExpiries[f]; hasExpiry {
+			result[i] = exp.UnixMilli()
+		} else {
+			result[i] = -1
+		}
+	}
+	return result, nil
+}
+
+// HPersist removes the expiry of each field, returning -2 for fields that do
+// not exist, -1 for those without an expiry and 1 for those that had one.
+func (s *Store) HPersist(key string, fields ...string) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]int, len(fields))
+	changed := false
+	for i, f := range fields {
+		if v == nil {
+			result[i] = -2
+			continue
+		}
+		if _, exists := v.hash[f]; !exists {
+			result[i] = -2
+			continue
+		}
+		if _, hasExpiry := v.fieldExpiries[f]; !hasExpiry {
+			result[i] = -1
+			continue
+		}
+		delete(v.fieldExpiries, f)
+		result[i] = 1
+		changed = true
+	}
+
+	if changed {
+		s.modified(key)
+	}
+	return result, nil
+}
+
+// setField sets a hash field, reporting whether it is new.
+func (v *value) setField(field, val string) bool {
+	_, exists := v.hash[field]
+	v.hash[field] = val
+	if !exists {
+		indexAdd(&v.index, field, len(v.hash), maps.Keys(v.hash))
+	}
+	return !exists
+}
+
+func (v *value) deleteField(field string) {
+	delete(v.hash, field)
+	delete(v.fieldExpiries, field)
+	indexRemove(v.index, field)
+}
+
+func (v *value) fieldExpired(field string, now time.Time) bool {
+	exp, hasExpiry := v.fieldExpiries[field]
+	return hasExpiry && now.After(exp)
+}
+
+// expiredHash reports whether every field of a hash has expired, in which
+// case the key no longer exists even if the fields haven't been deleted yet.
+func (v *value) expiredHash(now time.Time) bool {
+	if len(v.fieldExpiries) < len(v.hash) {
+		return false
+	}
+	for f := range v.hash {
+		if !v.fieldExpired(f, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// eachField calls fn with every field of a hash that has not expired.
+func (v *value) eachField(fn func(field, val string)) {
+	now := time.Now()
+	for f, val := range v.hash {
+		if !v.fieldExpired(f, now) {
+			fn(f, val)
+		}
+	}
+}
+
+// expireFields deletes the expired fields of the hash at key and reports
+// whether the key still exists afterwards. The caller must hold the write
+// lock.
+func (s *Store) expireFields(key string, v *value, now time.Time) bool {
+	expired := false
+	for f, exp := range v.fieldExpiries {
+		if now.After(exp) {
+			v.deleteField(f)
+			s.expireStats.ExpiredFields++
+			expired = true
+		}
+	}
+	if len(v.fieldExpiries) == 0 {
+		v.fieldExpiries = nil
+		s.hashExpiries.delete(key)
+	} else {
+		s.hashExpiries.set(key, earliest(v.fieldExpiries))
+	}
+	if expired {
+		s.touch(key)
+		s.removeIfEmpty(key, v)
+	}
+	return len(v.hash) > 0
+}
+
+func earliest(expiries map[string]time.Time) time.Time {
+	var first time.Time
+	for _, exp := range expiries {
+		if first.IsZero() || exp.Before(first) {
+			first = exp
+		}
+	}
+	return first
+}
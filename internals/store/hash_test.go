@@ -0,0 +1,153 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHDelRemovesEmptyHash(t *testing.T) {
+	s := NewStore()
+	if n, _ := s.HSet("h", map[string]string{"a": "1", "b": "2"}); n != 2 {
+		t.Fatalf("HSet added %d, want 2", n)
+	}
+	if n, _ := s.HSet("h", map[string]string{"a": "3", "c": "4"}); n != 1 {
+		t.Fatalf("HSet added %d, want 1", n)
+	}
+
+	if n, _ := s.HDel("h", "a", "b", "c", "missing"); n != 3 {
+		t.Fatalf("HDel removed %d, want 3", n)
+	}
+	if s.Exists("h") {
+		t.Fatal("empty hash was not removed")
+	}
+}
+
+func TestHIncrBy(t *testing.T) {
+	s := NewStore()
+	if n, err := s.HIncrBy("h", "f", 5); err != nil || n != 5 {
+		t.Fatalf("HIncrBy = %d, %v", n, err)
+	}
+	s.HSet("h", map[string]string{"s": "abc", "max": "9223372036854775807"})
+	if _, err := s.HIncrBy("h", "s", 1); !errors.Is(err, ErrHashNotInteger) {
+		t.Fatalf("HIncrBy on a non-integer returned %v", err)
+	}
+	if _, err := s.HIncrBy("h", "max", 1); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("HIncrBy past MaxInt64 returned %v", err)
+	}
+	if val, err := s.HIncrByFloat("h", "f", 0.5); err != nil || val != "5.5" {
+		t.Fatalf("HIncrByFloat = %q, %v", val, err)
+	}
+}
+
+func TestHRandFieldCounts(t *testing.T) {
+	s := NewStore()
+	s.HSet("h", map[string]string{"a": "1", "b": "2", "c": "3"})
+
+	fields, values, _ := s.HRandField("h", 10)
+	if len(fields) != 3 || len(values) != 3 {
+		t.Fatalf("positive count returned %d fields, want 3", len(fields))
+	}
+	seen := map[string]bool{}
+	for i, f := range fields {
+		if seen[f] {
+			t.Fatalf("field %q returned twice", f)
+		}
+		seen[f] = true
+		if val, _, _ := s.HGet("h", f); val != values[i] {
+			t.Fatalf("value for %q is %q, want %q", f, values[i], val)
+		}
+	}
+
+	if fields, _, _ := s.HRandField("h", -10); len(fields) != 10 {
+		t.Fatalf("negative count returned %d fields, want 10", len(fields))
+	}
+}
+
+func TestFieldExpiry(t *testing.T) {
+	s := NewStore()
+	s.HSet("h", map[string]string{"a": "1", "b": "2"})
+
+	past := time.Now().Add(-time.Millisecond)
+	future := time.Now().Add(time.Hour)
+	result, _ := s.HExpireAt("h", future, ExpireAlways, "a", "missing")
+	if result[0] != 1 || result[1] != -2 {
+		t.Fatalf("HExpireAt = %v, want [1 -2]", result)
+	}
+	if result, _ := s.HExpireAt("h", future.Add(time.Hour), ExpireLT, "a"); result[0] != 0 {
+		t.Fatalf("HExpireAt LT with a later time = %v, want [0]", result)
+	}
+
+	// Setting a field clears its expiry.
+	s.HSet("h", map[string]string{"a": "3"})
+	if exp, _ := s.HExpireTime("h", "a"); exp[0] != -1 {
+		t.Fatalf("expiry after HSet is %d, want -1", exp[0])
+	}
+
+	// Expired fields are hidden from readers before they are deleted.
+	s.HExpireAt("h", future, ExpireAlways, "a", "b")
+	s.mu.Lock()
+	s.keys["h"].fieldExpiries["a"] = past
+	s.mu.Unlock()
+	if _, ok, _ := s.HGet("h", "a"); ok {
+		t.Fatal("HGet returned an expired field")
+	}
+	if all, _ := s.HGetAll("h"); len(all) != 1 {
+		t.Fatalf("HGetAll returned %v", all)
+	}
+
+	s.mu.Lock()
+	s.keys["h"].fieldExpiries["b"] = past
+	s.mu.Unlock()
+	if s.Exists("h") {
+		t.Fatal("hash whose fields all expired still exists")
+	}
+	if result, _ := s.HPersist("h", "b"); result[0] != -2 {
+		t.Fatalf("HPersist on an expired field = %v, want [-2]", result)
+	}
+	if _, ok := s.keys["h"]; ok {
+		t.Fatal("write did not delete the expired hash")
+	}
+}
+
+func TestHRandFieldOfIndexedHash(t *testing.T) {
+	s := NewStore()
+	fields := map[string]string{}
+	for i := range 1000 {
+		fields[strconv.Itoa(i)] = "v" + strconv.Itoa(i)
+	}
+	s.HSet("h", fields)
+
+	// Most fields expire, HRANDFIELD must only return the others.
+	var expiring []string
+	for i := 100; i < 1000; i++ {
+		expiring = append(expiring, strconv.Itoa(i))
+	}
+	s.HExpireAt("h", time.Now().Add(10*time.Millisecond), ExpireAlways, expiring...)
+	time.Sleep(20 * time.Millisecond)
+
+	for _, count := range []int{1, 5, 50, -5, -500} {
+		got, values, err := s.HRandField("h", count)
+		want := count
+		if want < 0 {
+			want = -want
+		}
+		if err != nil || len(got) != want {
+			t.Fatalf("HRandField(%d) = %d fields, %v", count, len(got), err)
+		}
+		seen := map[string]bool{}
+		for i, f := range got {
+			if n, _ := strconv.Atoi(f); n >= 100 {
+				t.Fatalf("HRandField(%d) returned expired field %q", count, f)
+			}
+			if count > 0 && seen[f] {
+				t.Fatalf("HRandField(%d) returned %q twice", count, f)
+			}
+			seen[f] = true
+			if values[i] != "v"+f {
+				t.Fatalf("value for %q is %q", f, values[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package store
+
+import (
+	"maps"
+	"math/rand"
+)
+
+// lazyFreeThreshold is the number of elements above which UNLINK releases a
+// value on a background goroutine rather than on the caller's.
+const lazyFreeThreshold = 64
+
+// Rename moves the value at src, with its expiry, to dst, replacing whatever
+// dst held. With nx set nothing happens if dst exists, and false is
+// returned.
+func (s *Store) Rename(src, dst string, nx bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := s.lookupWrite(src)
+	if v == nil {
+		return false, ErrNoSuchKey
+	}
+	if nx && s.lookupWrite(dst) != nil {
+		return false, nil
+	}
+	if src == dst {
+		return true, nil
+	}
+
+	expiry, hasExpiry := s.expiries.get(src)
+	fieldExpiry, hasFieldExpiry := s.hashExpiries.get(src)
+	s.remove(src)
+	s.remove(dst)
+	s.add(dst, v)
+	if hasExpiry {
+		s.expiries.set(dst, expiry)
+	}
+	if hasFieldExpiry {
+		s.hashExpiries.set(dst, fieldExpiry)
+	}
+	if v.typ == TypeList {
+		s.signalReady(dst)
+	}
+	s.modified(src)
+	s.modified(dst)
+	return true, nil
+}
+
+// Copy stores a copy of the value at src, with its expiry, at dst in the
+// database to, which may be s itself. Unless replace is set nothing happens
+// if dst exists. It reports whether the value was copied.
+func (s *Store) Copy(src string, to *Store, dst string, replace bool) bool {
+	unlock := lockPair(s, to)
+	defer unlock()
+
+	v := s.lookupWrite(src)
+	if v == nil || (s == to && src == dst) {
+		return false
+	}
+	if to.lookupWrite(dst) != nil && !replace {
+		return false
+	}
+
+	c := v.copy()
+	to.remove(dst)
+	to.add(dst, c)
+	if expiry, ok := s.expiries.get(src); ok {
+		to.expiries.set(dst, expiry)
+	}
+	if c.fieldExpiries != nil {
+		to.hashExpiries.set(dst, earliest(c.fieldExpiries))
+	}
+	if c.typ == TypeList {
+		to.signalReady(dst)
+	}
+	to.modified(dst)
+	return true
+}
+
+// Move moves key, with its expiry, to the database to. Nothing happens if
+// key does not exist or already exists in to, and false is returned.
+func (s *Store) Move(key string, to *Store) bool {
+	unlock := lockPair(s, to)
+	defer unlock()
+
+	v := s.lookupWrite(key)
+	if v == nil || to.lookupWrite(key) != nil {
+		return false
+	}
+
+	expiry, hasExpiry := s.expiries.get(key)
+	fieldExpiry, hasFieldExpiry := s.hashExpiries.get(key)
+	s.remove(key)
+	to.add(key, v)
+	if hasExpiry {
+		to.expiries.set(key, expiry)
+	}
+	if hasFieldExpiry {
+		to.hashExpiries.set(key, fieldExpiry)
+	}
+	if v.typ == TypeList {
+		to.signalReady(key)
+	}
+	s.modified(key)
+	to.modified(key)
+	return true
+}
+
+// lockPair takes the write locks of two databases, which may be the same
+// one, and returns a function releasing them. Only commands take more than
+// one database lock and they run one at a time, so the order cannot
+// deadlock.
+func lockPair(a, b *Store) (unlock func()) {
+	a.mu.Lock()
+	if a == b {
+		return a.mu.Unlock
+	}
+	b.mu.Lock()
+	return func() {
+		b.mu.Unlock()
+		a.mu.Unlock()
+	}
+}
+
+// copy returns a deep copy of v.
+func (v *value) copy() *value {
+	c := &value{typ: v.typ, str: v.str, num: v.num, isInt: v.isInt}
+	switch v.typ {
+	case TypeList:
+		c.list = newQuicklist(v.list.values()...)
+	case TypeHash:
+		c.hash = maps.Clone(v.hash)
+		c.index = newIndex(len(c.hash), maps.Keys(c.hash))
+		if len(v.fieldExpiries) > 0 {
+			c.fieldExpiries = maps.Clone(v.fieldExpiries)
+		}
+	case TypeSet:
+		c.set = maps.Clone(v.set)
+		c.index = newIndex(len(c.set), maps.Keys(c.set))
+	case TypeZSet:
+		c.zset = newZSet()
+		for m, score := range v.zset.dict {
+			c.zset.set(m, score)
+		}
+	}
+	return c
+}
+
+// RandomKey returns a random live key, ok is false if there is none.
+func (s *Store) RandomKey() (key string, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Most keys are live, so a few random picks almost always find one.
+	// Fall back to walking the keyspace if they turn out to be expired.
+	for tries := 0; tries < 100 && s.index.count > 0; tries++ {
+		if key := s.index.random(); s.lookup(key) != nil {
+			return key, true
+		}
+	}
+	for key := range s.keys {
+		if s.lookup(key) != nil {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// DBSize returns the number of keys, including expired keys that have not
+// been deleted yet.
+func (s *Store) DBSize() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// Expires returns the number of keys with an expiry.
+func (s *Store) Expires() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.expiries.len()
+}
+
+// Flush deletes every key. With async set the old keyspace is released on
+// a background goroutine, otherwise it is cleared before Flush returns.
+func (s *Store) Flush(async bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, w := range s.watches {
+		if _, exists := s.keys[key]; exists {
+			w.version++
+		}
+	}
+	s.dirty += uint64(len(s.keys))
+
+	keys := s.keys
+	s.keys = make(map[string]*value)
+	s.index = newScanTable()
+	s.expiries = newExpiryTable()
+	s.hashExpiries = newExpiryTable()
+	if async {
+		go release(keys)
+	} else {
+		release(keys)
+	}
+}
+
+// Touch returns how many of keys exist.
+func (s *Store) Touch(keys ...string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, key := range keys {
+		if s.lookup(key) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Unlink deletes keys and returns how many existed. Large values are
+// released on a background goroutine, so unlinking them takes constant
+// time.
+func (s *Store) Unlink(keys ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var large map[string]*value
+	n := 0
+	for _, key := range keys {
+		v := s.lookupWrite(key)
+		if v == nil {
+			continue
+		}
+		s.remove(key)
+		s.modified(key)
+		n++
+		if v.size() > lazyFreeThreshold {
+			if large == nil {
+				large = make(map[string]*value)
+			}
+			large[key] = v
+		}
+	}
+	if large != nil {
+		go release(large)
+	}
+	return n
+}
+
+// size returns the number of elements in a container, 1 for a string.
+func (v *value) size() int {
+	switch v.typ {
+	case TypeList:
+		return v.list.len()
+	case TypeHash:
+		return len(v.hash)
+	case TypeSet:
+		return len(v.set)
+	case TypeZSet:
+		return len(v.zset.dict)
+	}
+	return 1
+}
+
+// release clears the containers of values that have been removed from the
+// store, which is work proportional to their size. Running it on its own
+// goroutine keeps that work off the event loop.
+func release(values map[string]*value) {
+	for key, v := range values {
+		switch v.typ {
+		case TypeList:
+			v.list = newQuicklist()
+		case TypeHash:
+			clear(v.hash)
+			v.fieldExpiries = nil
+		case TypeSet:
+			clear(v.set)
+		case TypeZSet:
+			clear(v.zset.dict)
+			v.zset.zsl = newSkiplist()
+		}
+		v.index = nil
+		delete(values, key)
+	}
+}
+
+// random returns a random string from the table, which must not be empty.
+func (t *scanTable) random() string {
+	for {
+		b := t.buckets
+		i := rand.Intn(len(t.buckets) + len(t.next))
+		if i >= len(t.buckets) {
+			b, i = t.next, i-len(t.buckets)
+		}
+		if len(b[i]) > 0 {
+			return b[i][rand.Intn(len(b[i]))]
+		}
+	}
+}
+
+// sample draws count strings from the table, distinct ones if distinct is
+// set, skipping those live rejects when it is not nil. Its cost follows
+// count rather than the size of the table, so callers only use it when
+// count is well below the size. It returns false if too many draws were
+// rejected, leaving the caller to fall back on walking every string.
+func (t *scanTable) sample(count int, distinct bool, live func(string) bool) ([]string, bool) {
+	result := make([]string, 0, count)
+	var seen map[string]struct{}
+	if distinct {
+		seen = make(map[string]struct{}, count)
+	}
+	for misses := 0; len(result) < count; {
+		s := t.random()
+		if _, dup := seen[s]; dup || (live != nil && !live(s)) {
+			if misses++; misses > 3*count+100 {
+				return nil, false
+			}
+			continue
+		}
+		if distinct {
+			seen[s] = struct{}{}
+		}
+		result = append(result, s)
+	}
+	return result, true
+}
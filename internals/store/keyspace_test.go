@@ -0,0 +1,93 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRenameKeepsExpiries(t *testing.T) {
+	s := NewStore()
+	at := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	s.HSet("src", map[string]string{"f": "1", "g": "2"})
+	s.HExpireAt("src", at, ExpireAlways, "f")
+	s.ExpireAt("src", at, ExpireAlways)
+	s.Set("dst", "old")
+
+	if ok, err := s.Rename("src", "dst", true); ok || err != nil {
+		t.Fatalf("RENAMENX onto an existing key = %v, %v", ok, err)
+	}
+	if _, err := s.Rename("missing", "dst", false); err != ErrNoSuchKey {
+		t.Fatalf("renaming a missing key returned %v", err)
+	}
+	if ok, err := s.Rename("src", "dst", false); !ok || err != nil {
+		t.Fatalf("Rename = %v, %v", ok, err)
+	}
+	if s.Exists("src") {
+		t.Fatal("source still exists after Rename")
+	}
+	if got, _ := s.ExpireTime("dst"); !got.Equal(at) {
+		t.Fatalf("key expiry is %v, want %v", got, at)
+	}
+	if got, _ := s.HExpireTime("dst", "f", "g"); got[0] != at.UnixMilli() || got[1] != -1 {
+		t.Fatalf("field expiries are %v", got)
+	}
+}
+
+func TestCopyIsIndependent(t *testing.T) {
+	s := NewStore()
+	for i := range 200 {
+		s.SAdd("src", fmt.Sprint(i))
+	}
+	if !s.Copy("src", s, "dst", false) || s.Copy("src", s, "dst", false) {
+		t.Fatal("Copy should succeed once without REPLACE")
+	}
+	s.SRem("dst", "0")
+	if n, _ := s.SCard("src"); n != 200 {
+		t.Fatalf("source has %d members after changing the copy", n)
+	}
+
+	seen := 0
+	for cursor := uint64(0); ; {
+		var members []string
+		cursor, members, _ = s.SScan("dst", cursor, ScanOptions{Count: 10})
+		seen += len(members)
+		if cursor == 0 {
+			break
+		}
+	}
+	if seen != 199 {
+		t.Fatalf("scanning the copy returned %d members, want 199", seen)
+	}
+}
+
+func TestFlushAndUnlink(t *testing.T) {
+	s := NewStore()
+	for i := range 100 {
+		s.RPush("list", fmt.Sprint(i))
+	}
+	s.Set("a", "1")
+	s.Set("b", "2")
+
+	if n := s.Unlink("list", "a", "missing"); n != 2 {
+		t.Fatalf("Unlink removed %d keys, want 2", n)
+	}
+	if s.DBSize() != 1 {
+		t.Fatalf("DBSize is %d after Unlink, want 1", s.DBSize())
+	}
+	if key, ok := s.RandomKey(); !ok || key != "b" {
+		t.Fatalf("RandomKey = %q, %v", key, ok)
+	}
+
+	s.Flush(true)
+	if s.DBSize() != 0 || s.Touch("b") != 0 {
+		t.Fatal("keys remain after Flush")
+	}
+	if _, ok := s.RandomKey(); ok {
+		t.Fatal("RandomKey found a key in an empty store")
+	}
+	s.Set("c", "3")
+	if s.DBSize() != 1 {
+		t.Fatal("store unusable after Flush")
+	}
+}
@@ -0,0 +1,456 @@
+package store
+
+import (
+	"errors"
+	"slices"
+)
+
+var (
+	ErrNoSuchKey       = errors.New("no such key")
+	ErrIndexOutOfRange = errors.New("index out of range")
+)
+
+func (s *Store) LPush(key string, values ...string) (int, error) {
+	return s.push(key, values, true, true)
+}
+
+func (s *Store) RPush(key string, values ...string) (int, error) {
+	return s.push(key, values, false, true)
+}
+
+// LPushX is LPush that only pushes onto an existing list.
+func (s *Store) LPushX(key string, values ...string) (int, error) {
+	return s.push(key, values, true, false)
+}
+
+// RPushX is RPush that only pushes onto an existing list.
+func (s *Store) RPushX(key string, values ...string) (int, error) {
+	return s.push(key, values, false, false)
+}
+
+func (s *Store) push(key string, values []string, left, create bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeList)
+	if err != nil {
+		return 0, err
+	}
+	if v == nil {
+		if !create {
+			return 0, nil
+		}
+		v = &value{typ: TypeList, list: newQuicklist()}
+		s.add(key, v)
+	}
+
+	for _, val := range values {
+		if left {
+			v.list.pushFront(val)
+		} else {
+			v.list.pushBack(val)
+		}
+	}
+	s.modified(key)
+	s.signalReady(key)
+	return v.list.len(), nil
+}
+
+func (s *Store) LPop(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeList)
+	if err != nil || v == nil {
+		return "", false, err
+	}
+	val := v.list.popFront()
+	s.removeIfEmpty(key, v)
+	s.modified(key)
+	return val, true, nil
+}
+
+func (s *Store) RPop(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeList)
+	if err != nil || v == nil {
+		return "", false, err
+	}
+	val := v.list.popBack()
+	s.removeIfEmpty(key, v)
+	s.modified(key)
+	return val, true, nil
+}
+
+// LPopCount pops up to count elements from the head of the list. The
+// result is nil if the key does not exist.
+func (s *Store) LPopCount(key string, count int) ([]string, error) {
+	return s.popCount(key, count, true)
+}
+
+// RPopCount pops up to count elements from the tail of the list.
+func (s *Store) RPopCount(key string, count int) ([]string, error) {
+	return s.popCount(key, count, false)
+}
+
+func (s *Store) popCount(key string, count int, left bool) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeList)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	popped := popList(v, count, left)
+	if len(popped) > 0 {
+		s.removeIfEmpty(key, v)
+		s.modified(key)
+	}
+	return popped, nil
+}
+
+func (s *Store) LLen(key string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeList)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return v.list.len(), nil
+}
+
+// LRange returns the elements between start and stop inclusive, negative
+// indexes counting back from the tail.
+func (s *Store) LRange(key string, start, stop int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeList)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	start, stop, ok := listRange(v.list.len(), start, stop)
+	if !ok {
+		return nil, nil
+	}
+	return v.list.slice(start, stop), nil
+}
+
+func (s *Store) LIndex(key string, index int) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeList)
+	if err != nil || v == nil {
+		return "", false, err
+	}
+	i, ok := listIndex(v.list.len(), index)
+	if !ok {
+		return "", false, nil
+	}
+	return v.list.at(i), true, nil
+}
+
+func (s *Store) LSet(key string, index int, val string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeList)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return ErrNoSuchKey
+	}
+	i, ok := listIndex(v.list.len(), index)
+	if !ok {
+		return ErrIndexOutOfRange
+	}
+	v.list.set(i, val)
+	s.modified(key)
+	return nil
+}
+
+// LInsert inserts val before or after the first occurrence of pivot and
+// returns the new length, -1 if pivot was not found and 0 if the key does
+// not exist.
+func (s *Store) LInsert(key string, before bool, pivot, val string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeList)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	i := -1
+	v.list.each(false, func(j int, e string) bool {
+		if e == pivot {
+			i = j
+			return false
+		}
+		return true
+	})
+	if i < 0 {
+		return -1, nil
+	}
+	if !before {
+		i++
+	}
+	v.list.insert(i, val)
+	s.modified(key)
+	s.signalReady(key)
+	return v.list.len(), nil
+}
+
+// LRem removes up to count occurrences of val scanning from the head, or
+// from the tail when count is negative. A count of 0 removes them all.
+func (s *Store) LRem(key string, count int, val string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeList)
+	if err != nil || v == nil {
+		return 0, err
+	}
+
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+	removed := 0
+	kept := make([]string, 0, v.list.len())
+	v.list.each(count < 0, func(_ int, e string) bool {
+		if e == val && (limit == 0 || removed < limit) {
+			removed++
+		} else {
+			kept = append(kept, e)
+		}
+		return true
+	})
+	if count < 0 {
+		slices.Reverse(kept)
+	}
+
+	if removed > 0 {
+		v.list = newQuicklist(kept...)
+		s.removeIfEmpty(key, v)
+		s.modified(key)
+	}
+	return removed, nil
+}
+
+// LTrim keeps only the elements between start and stop inclusive.
+func (s *Store) LTrim(key string, start, stop int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeList)
+	if err != nil || v == nil {
+		return err
+	}
+	n := v.list.len()
+	start, stop, ok := listRange(n, start, stop)
+	if !ok {
+		v.list.removeFront(n)
+	} else {
+		v.list.removeBack(n - 1 - stop)
+		v.list.removeFront(start)
+	}
+	s.removeIfEmpty(key, v)
+	s.modified(key)
+	return nil
+}
+
+// LPosOptions control LPos: Rank picks which match to start from, negative
+// ranks scanning from the tail; Count is how many matches to return, 0
+// meaning all; MaxLen bounds how many elements are compared, 0 meaning no
+// limit.
+type LPosOptions struct {
+	Rank   int
+	Count  int
+	MaxLen int
+}
+
+// LPos returns the indexes of elements equal to val.
+func (s *Store) LPos(key, val string, opts LPosOptions) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeList)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	skip := opts.Rank - 1
+	if opts.Rank < 0 {
+		skip = -opts.Rank - 1
+	}
+
+	var positions []int
+	scanned := 0
+	v.list.each(opts.Rank < 0, func(i int, e string) bool {
+		if opts.MaxLen > 0 && scanned >= opts.MaxLen {
+			return false
+		}
+		scanned++
+		if e != val {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		positions = append(positions, i)
+		return opts.Count == 0 || len(positions) < opts.Count
+	})
+	return positions, nil
+}
+
+// listIndex resolves a possibly negative index into a list of length n.
+func listIndex(n, index int) (int, bool) {
+	if index < 0 {
+		index += n
+	}
+	return index, index >= 0 && index < n
+}
+
+// listRange clamps an inclusive, possibly negative, range to a list of
+// length n, ok is false if the range is empty.
+func listRange(n, start, stop int) (int, int, bool) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	start = max(start, 0)
+	if start > stop || start >= n {
+		return 0, 0, false
+	}
+	return start, min(stop, n-1), true
+}
+
+// LMove atomically pops an element from one end of src and pushes it onto
+// one end of dst, the left end when the respective flag is set.
+func (s *Store) LMove(src, dst string, fromLeft, toLeft bool) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, err := s.lookupWriteType(src, TypeList)
+	if err != nil || from == nil {
+		return "", false, err
+	}
+	to, err := s.lookupWriteType(dst, TypeList)
+	if err != nil {
+		return "", false, err
+	}
+
+	if to == nil {
+		to = &value{typ: TypeList, list: newQuicklist()}
+		s.add(dst, to)
+	}
+
+	// Push before removing an emptied src, as src and dst may be the same
+	// list.
+	val := popList(from, 1, fromLeft)[0]
+	if toLeft {
+		to.list.pushFront(val)
+	} else {
+		to.list.pushBack(val)
+	}
+	s.removeIfEmpty(src, from)
+	s.modified(src)
+	s.modified(dst)
+	s.signalReady(dst)
+	return val, true, nil
+}
+
+// LMPop pops up to count elements from the first non-empty list among keys,
+// returning the key they were popped from.
+func (s *Store) LMPop(keys []string, count int, left bool) (string, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		v, err := s.lookupWriteType(key, TypeList)
+		if err != nil {
+			return "", nil, err
+		}
+		if v == nil {
+			continue
+		}
+		popped := popList(v, count, left)
+		s.removeIfEmpty(key, v)
+		s.modified(key)
+		return key, popped, nil
+	}
+	return "", nil, nil
+}
+
+// popList removes up to count elements from one end of a list value, in the
+// order they are popped.
+func popList(v *value, count int, left bool) []string {
+	popped := make([]string, min(count, v.list.len()))
+	for i := range popped {
+		if left {
+			popped[i] = v.list.popFront()
+		} else {
+			popped[i] = v.list.popBack()
+		}
+	}
+	return popped
+}
+
+// BlockOn records that a client is blocked waiting for key to receive
+// elements. Every BlockOn must be paired with an UnblockOn.
+func (s *Store) BlockOn(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocking[key]++
+}
+
+// Blocked returns how many clients are blocked on key.
+func (s *Store) Blocked(key string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.blocking[key]
+}
+
+func (s *Store) UnblockOn(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blocking[key]--
+	if s.blocking[key] <= 0 {
+		delete(s.blocking, key)
+		delete(s.ready, key)
+	}
+}
+
+// ReadyKeys returns the keys that clients are blocked on and that were
+// pushed to since the last call.
+func (s *Store) ReadyKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.ready) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(s.ready))
+	for key := range s.ready {
+		keys = append(keys, key)
+	}
+	clear(s.ready)
+	return keys
+}
+
+// signalReady marks key as ready if a client is blocked on it. The caller
+// must hold the write lock.
+func (s *Store) signalReady(key string) {
+	if s.blocking[key] > 0 {
+		s.ready[key] = struct{}{}
+	}
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func newList(t *testing.T, values ...string) *Store {
+	t.Helper()
+	s := NewStore()
+	if _, err := s.RPush("l", values...); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLRangeNegativeIndexes(t *testing.T) {
+	s := newList(t, "a", "b", "c", "d", "e")
+
+	tests := []struct {
+		start, stop int
+		want        []string
+	}{
+		{0, -1, []string{"a", "b", "c", "d", "e"}},
+		{-3, -2, []string{"c", "d"}},
+		{-100, 1, []string{"a", "b"}},
+		{3, 100, []string{"d", "e"}},
+		{4, 2, nil},
+		{5, 10, nil},
+	}
+	for _, tt := range tests {
+		got, err := s.LRange("l", tt.start, tt.stop)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("LRange(%d, %d) = %v, want %v", tt.start, tt.stop, got, tt.want)
+		}
+	}
+}
+
+func TestLRemDirections(t *testing.T) {
+	tests := []struct {
+		count int
+		want  []string
+	}{
+		{2, []string{"b", "x", "c", "x"}},
+		{-2, []string{"x", "b", "x", "c"}},
+		{0, []string{"b", "c"}},
+	}
+	for _, tt := range tests {
+		s := newList(t, "x", "b", "x", "x", "c", "x")
+		if _, err := s.LRem("l", tt.count, "x"); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := s.LRange("l", 0, -1)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("LRem(%d) left %v, want %v", tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestLPosRankCountMaxLen(t *testing.T) {
+	s := newList(t, "a", "b", "c", "1", "2", "3", "c", "c")
+
+	tests := []struct {
+		opts LPosOptions
+		want []int
+	}{
+		{LPosOptions{Rank: 1, Count: 1}, []int{2}},
+		{LPosOptions{Rank: 2, Count: 1}, []int{6}},
+		{LPosOptions{Rank: -1, Count: 1}, []int{7}},
+		{LPosOptions{Rank: 1, Count: 0}, []int{2, 6, 7}},
+		{LPosOptions{Rank: -2, Count: 2}, []int{6, 2}},
+		{LPosOptions{Rank: 1, Count: 0, MaxLen: 3}, []int{2}},
+		{LPosOptions{Rank: 4, Count: 1}, nil},
+	}
+	for _, tt := range tests {
+		got, err := s.LPos("l", "c", tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("LPos(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestLTrimAndInsert(t *testing.T) {
+	s := newList(t, "a", "b", "c", "d")
+
+	if err := s.LTrim("l", 1, -2); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.LInsert("l", true, "c", "x"); n != 3 {
+		t.Fatalf("LInsert returned %d, want 3", n)
+	}
+	if n, _ := s.LInsert("l", false, "missing", "y"); n != -1 {
+		t.Fatalf("LInsert with a missing pivot returned %d, want -1", n)
+	}
+	got, _ := s.LRange("l", 0, -1)
+	if want := []string{"b", "x", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("list is %v, want %v", got, want)
+	}
+
+	if err := s.LTrim("l", 5, 10); err != nil {
+		t.Fatal(err)
+	}
+	if s.Exists("l") {
+		t.Fatal("list trimmed to nothing still exists")
+	}
+}
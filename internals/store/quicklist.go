@@ -0,0 +1,355 @@
+package store
+
+// quicklistChunkSize is how many elements a single quicklist node holds.
+// A node starts out with room for quicklistNodeMin and doubles its buffer
+// as it fills, so a short list stays small.
+const (
+	quicklistChunkSize = 128
+	quicklistNodeMin   = 4
+)
+
+// quicklist stores a list as a doubly linked list of fixed-size chunks, so
+// pushing and popping at either end is O(1) without ever copying the whole
+// list. Popped slots are cleared and emptied chunks released, so the memory
+// held beyond the elements themselves is bounded by the number of chunks.
+type quicklist struct {
+	head   *quicklistNode
+	tail   *quicklistNode
+	length int
+
+	// spare is the last node emptied by a pop, kept so that alternating
+	// pushes and pops at a node boundary don't allocate every time.
+	spare *quicklistNode
+}
+
+// quicklistNode keeps its elements in buf[start:end]. Nodes created at the
+// head fill buf from the back and nodes created at the tail from the front,
+// so either end can grow without shifting. buf grows up to
+// quicklistChunkSize elements.
+type quicklistNode struct {
+	prev, next *quicklistNode
+	buf        []string
+	start, end int
+}
+
+func newQuicklist(values ...string) *quicklist {
+	ql := &quicklist{}
+	for _, v := range values {
+		ql.pushBack(v)
+	}
+	return ql
+}
+
+func (ql *quicklist) newNode(atHead bool) *quicklistNode {
+	n := ql.spare
+	if n != nil {
+		ql.spare = nil
+		n.start, n.end = 0, 0
+	} else {
+		n = &quicklistNode{buf: make([]string, quicklistNodeMin)}
+	}
+	if atHead {
+		n.start, n.end = len(n.buf), len(n.buf)
+	}
+	return n
+}
+
+func (n *quicklistNode) len() int {
+	return n.end - n.start
+}
+
+func (n *quicklistNode) full() bool {
+	return n.len() == quicklistChunkSize
+}
+
+// makeRoom makes space for one more element at the front or the back of a
+// node that is not full, doubling buf if the elements fill it and sliding
+// them away from that end otherwise.
+func (n *quicklistNode) makeRoom(front bool) {
+	if (front && n.start > 0) || (!front && n.end < len(n.buf)) {
+		return
+	}
+	size := n.len()
+	buf := n.buf
+	if size == len(buf) {
+		buf = make([]string, min(2*len(buf), quicklistChunkSize))
+	}
+	if front {
+		shift := len(buf) - size
+		copy(buf[shift:], n.buf[n.start:n.end])
+		if size < len(n.buf) {
+			clear(buf[:shift])
+		}
+		n.start, n.end = shift, len(buf)
+	} else {
+		copy(buf, n.buf[n.start:n.end])
+		if size < len(n.buf) {
+			clear(buf[size:n.end])
+		}
+		n.start, n.end = 0, size
+	}
+	n.buf = buf
+}
+
+func (ql *quicklist) len() int {
+	return ql.length
+}
+
+func (ql *quicklist) pushFront(v string) {
+	n := ql.head
+	if n == nil || n.full() {
+		n = ql.newNode(true)
+		ql.linkAfter(nil, n)
+	}
+	n.makeRoom(true)
+	n.start--
+	n.buf[n.start] = v
+	ql.length++
+}
+
+func (ql *quicklist) pushBack(v string) {
+	n := ql.tail
+	if n == nil || n.full() {
+		n = ql.newNode(false)
+		ql.linkAfter(ql.tail, n)
+	}
+	n.makeRoom(false)
+	n.buf[n.end] = v
+	n.end++
+	ql.length++
+}
+
+func (ql *quicklist) popFront() string {
+	n := ql.head
+	v := n.buf[n.start]
+	n.buf[n.start] = ""
+	n.start++
+	ql.length--
+	if n.len() == 0 {
+		ql.unlink(n)
+		ql.spare = n
+	}
+	return v
+}
+
+func (ql *quicklist) popBack() string {
+	n := ql.tail
+	n.end--
+	v := n.buf[n.end]
+	n.buf[n.end] = ""
+	ql.length--
+	if n.len() == 0 {
+		ql.unlink(n)
+		ql.spare = n
+	}
+	return v
+}
+
+// removeFront drops the first count elements, releasing whole nodes at once.
+func (ql *quicklist) removeFront(count int) {
+	for count > 0 && ql.head != nil {
+		n := ql.head
+		k := min(count, n.len())
+		clear(n.buf[n.start : n.start+k])
+		n.start += k
+		ql.length -= k
+		count -= k
+		if n.len() == 0 {
+			ql.unlink(n)
+		}
+	}
+}
+
+// removeBack drops the last count elements.
+func (ql *quicklist) removeBack(count int) {
+	for count > 0 && ql.tail != nil {
+		n := ql.tail
+		k := min(count, n.len())
+		clear(n.buf[n.end-k : n.end])
+		n.end -= k
+		ql.length -= k
+		count -= k
+		if n.len() == 0 {
+			ql.unlink(n)
+		}
+	}
+}
+
+// locate returns the node holding index i and the offset of i within it,
+// walking from whichever end is closer.
+func (ql *quicklist) locate(i int) (*quicklistNode, int) {
+	if i < ql.length/2 {
+		for n := ql.head; ; n = n.next {
+			if i < n.len() {
+				return n, i
+			}
+			i -= n.len()
+		}
+	}
+	i = ql.length - 1 - i
+	for n := ql.tail; ; n = n.prev {
+		if i < n.len() {
+			return n, n.len() - 1 - i
+		}
+		i -= n.len()
+	}
+}
+
+// at returns the element at index i, which must be in range.
+func (ql *quicklist) at(i int) string {
+	n, off := ql.locate(i)
+	return n.buf[n.start+off]
+}
+
+func (ql *quicklist) set(i int, v string) {
+	n, off := ql.locate(i)
+	n.buf[n.start+off] = v
+}
+
+// insert places v at index i, shifting later elements back. i may equal the
+// length to append.
+func (ql *quicklist) insert(i int, v string) {
+	switch i {
+	case 0:
+		ql.pushFront(v)
+		return
+	case ql.length:
+		ql.pushBack(v)
+		return
+	}
+
+	n, off := ql.locate(i)
+	if n.full() {
+		// Split the node at off, then merge the halves into their
+		// neighbours when they fit so splits don't leave sparse nodes.
+		m := ql.newNode(false)
+		if tail := n.len() - off; len(m.buf) < tail {
+			m.buf = make([]string, tail)
+		}
+		moved := copy(m.buf, n.buf[n.start+off:n.end])
+		clear(n.buf[n.start+off : n.end])
+		m.end = moved
+		n.end = n.start + off
+		ql.linkAfter(n, m)
+		n.insertAt(n.len(), v)
+		ql.length++
+		ql.mergeNext(m)
+		if n.prev != nil {
+			ql.mergeNext(n.prev)
+		}
+		return
+	}
+	n.insertAt(off, v)
+	ql.length++
+}
+
+// insertAt inserts v at offset off of a node that is not full.
+func (n *quicklistNode) insertAt(off int, v string) {
+	if n.start == 0 {
+		n.makeRoom(false)
+	}
+	if n.end < len(n.buf) {
+		copy(n.buf[n.start+off+1:n.end+1], n.buf[n.start+off:n.end])
+		n.end++
+	} else {
+		copy(n.buf[n.start-1:], n.buf[n.start:n.start+off])
+		n.start--
+	}
+	n.buf[n.start+off] = v
+}
+
+// mergeNext moves the elements of n.next into n if they fit.
+func (ql *quicklist) mergeNext(n *quicklistNode) {
+	next := n.next
+	if next == nil || n.len()+next.len() > quicklistChunkSize {
+		return
+	}
+	if size := n.len() + next.len(); size > len(n.buf) {
+		buf := make([]string, min(max(size, 2*len(n.buf)), quicklistChunkSize))
+		n.start, n.end = 0, copy(buf, n.buf[n.start:n.end])
+		n.buf = buf
+	} else if n.end+next.len() > len(n.buf) {
+		size := n.len()
+		copy(n.buf, n.buf[n.start:n.end])
+		clear(n.buf[size:n.end])
+		n.start, n.end = 0, size
+	}
+	n.end += copy(n.buf[n.end:], next.buf[next.start:next.end])
+	ql.unlink(next)
+}
+
+// each calls fn with every element and its index, from the tail when rev is
+// set, until fn returns false.
+func (ql *quicklist) each(rev bool, fn func(i int, v string) bool) {
+	if rev {
+		i := ql.length - 1
+		for n := ql.tail; n != nil; n = n.prev {
+			for j := n.end - 1; j >= n.start; j-- {
+				if !fn(i, n.buf[j]) {
+					return
+				}
+				i--
+			}
+		}
+		return
+	}
+	i := 0
+	for n := ql.head; n != nil; n = n.next {
+		for _, v := range n.buf[n.start:n.end] {
+			if !fn(i, v) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// slice returns a copy of the elements between start and stop inclusive,
+// both of which must be in range.
+func (ql *quicklist) slice(start, stop int) []string {
+	result := make([]string, 0, stop-start+1)
+	n, off := ql.locate(start)
+	for ; n != nil && len(result) < cap(result); n, off = n.next, 0 {
+		items := n.buf[n.start+off : n.end]
+		result = append(result, items[:min(len(items), cap(result)-len(result))]...)
+	}
+	return result
+}
+
+func (ql *quicklist) values() []string {
+	if ql.length == 0 {
+		return []string{}
+	}
+	return ql.slice(0, ql.length-1)
+}
+
+// linkAfter links n after prev, or at the head when prev is nil.
+func (ql *quicklist) linkAfter(prev, n *quicklistNode) {
+	n.prev = prev
+	if prev == nil {
+		n.next = ql.head
+		ql.head = n
+	} else {
+		n.next = prev.next
+		prev.next = n
+	}
+	if n.next == nil {
+		ql.tail = n
+	} else {
+		n.next.prev = n
+	}
+}
+
+func (ql *quicklist) unlink(n *quicklistNode) {
+	if n.prev == nil {
+		ql.head = n.next
+	} else {
+		n.prev.next = n.next
+	}
+	if n.next == nil {
+		ql.tail = n.prev
+	} else {
+		n.next.prev = n.prev
+	}
+	n.prev, n.next = nil, nil
+}
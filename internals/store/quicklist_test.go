@@ -0,0 +1,175 @@
+package store
+
+import (
+	"math/rand"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestQuicklistMatchesSlice(t *testing.T) {
+	ql := newQuicklist()
+	var want []string
+
+	for i := 0; i < 20000; i++ {
+		v := strconv.Itoa(i)
+		switch op := rand.Intn(10); {
+		case op < 3:
+			ql.pushFront(v)
+			want = slices.Insert(want, 0, v)
+		case op < 6:
+			ql.pushBack(v)
+			want = append(want, v)
+		case op < 7 && len(want) > 0:
+			if got := ql.popFront(); got != want[0] {
+				t.Fatalf("popFront() = %q, want %q", got, want[0])
+			}
+			want = want[1:]
+		case op < 8 && len(want) > 0:
+			if got := ql.popBack(); got != want[len(want)-1] {
+				t.Fatalf("popBack() = %q, want %q", got, want[len(want)-1])
+			}
+			want = want[:len(want)-1]
+		case op < 9:
+			j := rand.Intn(len(want) + 1)
+			ql.insert(j, v)
+			want = slices.Insert(want, j, v)
+		case len(want) > 0:
+			j := rand.Intn(len(want))
+			ql.set(j, v)
+			want[j] = v
+		}
+		if ql.len() != len(want) {
+			t.Fatalf("len() = %d, want %d", ql.len(), len(want))
+		}
+	}
+
+	if got := ql.values(); !slices.Equal(got, want) {
+		t.Fatal("values() does not match the model")
+	}
+	for j := range want {
+		if got := ql.at(j); got != want[j] {
+			t.Fatalf("at(%d) = %q, want %q", j, got, want[j])
+		}
+	}
+	var rev []string
+	ql.each(true, func(i int, v string) bool {
+		if v != want[i] {
+			t.Fatalf("each(rev) gave %q at %d, want %q", v, i, want[i])
+		}
+		rev = append(rev, v)
+		return true
+	})
+	if len(rev) != len(want) {
+		t.Fatalf("each(rev) visited %d elements, want %d", len(rev), len(want))
+	}
+
+	if len(want) > 10 {
+		ql.removeFront(3)
+		ql.removeBack(4)
+		want = want[3 : len(want)-4]
+		if got := ql.slice(0, ql.len()-1); !slices.Equal(got, want) {
+			t.Fatal("trimmed list does not match the model")
+		}
+	}
+}
+
+func TestQuicklistNodesStayDense(t *testing.T) {
+	ql := newQuicklist()
+	for i := 0; i < 10*quicklistChunkSize; i++ {
+		ql.insert(rand.Intn(ql.len()+1), strconv.Itoa(i))
+	}
+
+	nodes := 0
+	for n := ql.head; n != nil; n = n.next {
+		nodes++
+	}
+	// Adjacent nodes are merged whenever they fit into one, so on average
+	// every node is at least half full.
+	if limit := 2*ql.len()/quicklistChunkSize + 1; nodes > limit {
+		t.Fatalf("%d elements spread over %d nodes, want at most %d", ql.len(), nodes, limit)
+	}
+}
+
+func TestQuicklistSmallListsStaySmall(t *testing.T) {
+	lists := make([]*quicklist, 1000)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := range lists {
+		lists[i] = newQuicklist("x")
+	}
+	runtime.ReadMemStats(&after)
+
+	// A one element list holds a node and a buffer of quicklistNodeMin
+	// strings, not a whole chunk.
+	if perList := (after.TotalAlloc - before.TotalAlloc) / uint64(len(lists)); perList > 256 {
+		t.Fatalf("a one element list allocates %d bytes", perList)
+	}
+
+	// Growing a node one element at a time still fills it to a chunk.
+	ql := newQuicklist()
+	for i := range quicklistChunkSize {
+		ql.pushFront(strconv.Itoa(i))
+	}
+	if ql.head != ql.tail || len(ql.head.buf) != quicklistChunkSize {
+		t.Fatalf("%d elements did not fill a single node", quicklistChunkSize)
+	}
+}
+
+const benchListSize = 1_000_000
+
+func newBenchList(b *testing.B) *Store {
+	b.Helper()
+	s := NewStore()
+	values := make([]string, benchListSize)
+	for i := range values {
+		values[i] = strconv.Itoa(i)
+	}
+	if _, err := s.RPush("list", values...); err != nil {
+		b.Fatal(err)
+	}
+	return s
+}
+
+func BenchmarkLPushMillion(b *testing.B) {
+	s := newBenchList(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.LPush("list", "x")
+	}
+}
+
+func BenchmarkRPushMillion(b *testing.B) {
+	s := newBenchList(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.RPush("list", "x")
+	}
+}
+
+func BenchmarkLPushLPopMillion(b *testing.B) {
+	s := newBenchList(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.LPush("list", "x")
+		s.LPop("list")
+	}
+}
+
+func BenchmarkQueueMillion(b *testing.B) {
+	s := newBenchList(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.RPush("list", "x")
+		s.LPop("list")
+	}
+}
+
+func BenchmarkLIndexMiddleMillion(b *testing.B) {
+	s := newBenchList(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.LIndex("list", benchListSize/2)
+	}
+}
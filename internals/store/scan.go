@@ -0,0 +1,322 @@
+package store
+
+import (
+	"go_redis/internals/glob"
+	"hash/maphash"
+	"iter"
+	"maps"
+	"math/bits"
+	"time"
+)
+
+// collectionIndexMin is the size above which a hash, set or sorted set gets
+// a scan index. Smaller collections are returned whole by a single call.
+const collectionIndexMin = 128
+
+// ScanOptions filters what a scan returns. Count is a hint for how much work
+// a call does, not how many results it returns: filtering happens after the
+// elements have been collected, as in Redis.
+type ScanOptions struct {
+	Match string
+	Count int
+	// Type restricts SCAN to keys of the named type when set.
+	Type string
+}
+
+func (o ScanOptions) matches(s string) bool {
+	return o.Match == "" || glob.Match(o.Match, s)
+}
+
+// Keys returns every live key matching pattern.
+func (s *Store) Keys(pattern string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []string{}
+	for key := range s.keys {
+		if s.lookup(key) != nil && glob.Match(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Scan returns a batch of keys starting at cursor, and the cursor for the
+// next batch, 0 once every key has been visited. A key that exists for the
+// whole iteration is returned at least once.
+func (s *Store) Scan(cursor uint64, opts ScanOptions) (uint64, []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []string{}
+	next := s.index.scan(cursor, opts.Count, func(key string) {
+		v := s.lookup(key)
+		if v == nil || (opts.Type != "" && v.typ.String() != opts.Type) || !opts.matches(key) {
+			return
+		}
+		keys = append(keys, key)
+	})
+	return next, keys
+}
+
+// HScan is Scan for the fields of a hash, returned with their values.
+func (s *Store) HScan(key string, cursor uint64, opts ScanOptions) (uint64, []string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeHash)
+	if err != nil || v == nil {
+		return 0, []string{}, err
+	}
+	now := time.Now()
+	result := []string{}
+	next := scanCollection(v.index, cursor, opts, maps.Keys(v.hash), func(f string) {
+		if !v.fieldExpired(f, now) {
+			result = append(result, f, v.hash[f])
+		}
+	})
+	return next, result, nil
+}
+
+// SScan is Scan for the members of a set.
+func (s *Store) SScan(key string, cursor uint64, opts ScanOptions) (uint64, []string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeSet)
+	if err != nil || v == nil {
+		return 0, []string{}, err
+	}
+	result := []string{}
+	next := scanCollection(v.index, cursor, opts, maps.Keys(v.set), func(m string) {
+		result = append(result, m)
+	})
+	return next, result, nil
+}
+
+// ZScan is Scan for the members of a sorted set, returned with their scores.
+func (s *Store) ZScan(key string, cursor uint64, opts ScanOptions) (uint64, []ZMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeZSet)
+	if err != nil || v == nil {
+		return 0, []ZMember{}, err
+	}
+	result := []ZMember{}
+	next := scanCollection(v.zset.index, cursor, opts, maps.Keys(v.zset.dict), func(m string) {
+		result = append(result, ZMember{Member: m, Score: v.zset.dict[m]})
+	})
+	return next, result, nil
+}
+
+// scanCollection scans a collection through its index, or returns all of it
+// at once if it is too small to have one.
+func scanCollection(index *scanTable, cursor uint64, opts ScanOptions, all iter.Seq[string], fn func(string)) uint64 {
+	emit := func(m string) {
+		if opts.matches(m) {
+			fn(m)
+		}
+	}
+	if index == nil {
+		for m := range all {
+			emit(m)
+		}
+		return 0
+	}
+	return index.scan(cursor, opts.Count, emit)
+}
+
+// newIndex returns the scan index for a collection of size members, nil if
+// it is too small to need one.
+func newIndex(size int, all iter.Seq[string]) *scanTable {
+	if size <= collectionIndexMin {
+		return nil
+	}
+	t := newScanTable()
+	for m := range all {
+		t.add(m)
+	}
+	return t
+}
+
+// indexAdd records that member was added to a collection now holding size
+// members, building its index once it outgrows collectionIndexMin.
+func indexAdd(index **scanTable, member string, size int, all iter.Seq[string]) {
+	if *index != nil {
+		(*index).add(member)
+	} else {
+		*index = newIndex(size, all)
+	}
+}
+
+// indexRemove records that member was removed from a collection.
+func indexRemove(index *scanTable, member string) {
+	if index != nil {
+		index.remove(member)
+	}
+}
+
+// scanTable indexes a set of strings by hash so they can be iterated with a
+// cursor the way Redis scans its dicts: the cursor walks the buckets in
+// reverse binary order, so a string present for the whole iteration is
+// returned even if others are added or removed, or the table is resized,
+// between calls.
+//
+// Like a Redis dict it resizes incrementally. While rehashing, strings move
+// from the old buckets to the new ones a bucket at a time on every add and
+// remove, so no single call pays for rehashing the whole table.
+type scanTable struct {
+	seed    maphash.Seed
+	buckets [][]string
+	// next holds the resized buckets while rehashing. The old buckets below
+	// rehashed have been moved to it and are empty.
+	next     [][]string
+	rehashed int
+	count    int
+}
+
+const (
+	scanTableMinSize = 4
+	// rehashEmptyVisits bounds how many empty buckets a rehash step may
+	// skip, so a step stays cheap in a sparse table.
+	rehashEmptyVisits = 10
+)
+
+func newScanTable() *scanTable {
+	return &scanTable{seed: maphash.MakeSeed(), buckets: make([][]string, scanTableMinSize)}
+}
+
+func (t *scanTable) rehashing() bool {
+	return t.next != nil
+}
+
+func (t *scanTable) bucket(s string) *[]string {
+	h := maphash.String(t.seed, s)
+	if i := h & uint64(len(t.buckets)-1); !t.rehashing() || i >= uint64(t.rehashed) {
+		return &t.buckets[i]
+	}
+	return &t.next[h&uint64(len(t.next)-1)]
+}
+
+// add inserts s unless it is already present.
+func (t *scanTable) add(s string) {
+	t.rehashStep()
+	b := t.bucket(s)
+	for _, x := range *b {
+		if x == s {
+			return
+		}
+	}
+	*b = append(*b, s)
+	t.count++
+	if !t.rehashing() && t.count > len(t.buckets) {
+		t.resize(len(t.buckets) * 2)
+	}
+}
+
+func (t *scanTable) remove(s string) {
+	t.rehashStep()
+	b := t.bucket(s)
+	for i, x := range *b {
+		if x != s {
+			continue
+		}
+		last := len(*b) - 1
+		(*b)[i] = (*b)[last]
+		(*b)[last] = ""
+		*b = (*b)[:last]
+		t.count--
+		if !t.rehashing() && len(t.buckets) > scanTableMinSize && t.count < len(t.buckets)/8 {
+			t.resize(len(t.buckets) / 2)
+		}
+		return
+	}
+}
+
+// resize starts rehashing into size buckets.
+func (t *scanTable) resize(size int) {
+	t.next = make([][]string, size)
+	t.rehashed = 0
+}
+
+// rehashStep moves the next non-empty bucket to the resized table, and
+// finishes the rehash once every bucket has moved.
+func (t *scanTable) rehashStep() {
+	if !t.rehashing() {
+		return
+	}
+	for visits := 0; t.rehashed < len(t.buckets) && visits < rehashEmptyVisits; visits++ {
+		b := t.buckets[t.rehashed]
+		t.buckets[t.rehashed] = nil
+		t.rehashed++
+		if len(b) == 0 {
+			continue
+		}
+		mask := uint64(len(t.next) - 1)
+		for _, s := range b {
+			nb := &t.next[maphash.String(t.seed, s)&mask]
+			*nb = append(*nb, s)
+		}
+		break
+	}
+	if t.rehashed == len(t.buckets) {
+		t.buckets, t.next, t.rehashed = t.next, nil, 0
+	}
+}
+
+// scan calls fn with the strings of the buckets from cursor on, until about
+// count of them have been seen, and returns the cursor to continue from, 0
+// once the iteration is complete.
+func (t *scanTable) scan(cursor uint64, count int, fn func(string)) uint64 {
+	seen, empty := 0, 0
+	for {
+		var n int
+		cursor, n = t.scanStep(cursor, fn)
+		seen += n
+		if n == 0 {
+			empty++
+		}
+		if cursor == 0 || seen >= count || empty >= count*10 {
+			return cursor
+		}
+	}
+}
+
+// scanStep visits the bucket at cursor and returns the next cursor along
+// with how many strings it saw. While rehashing it visits the bucket of the
+// smaller table and every bucket of the larger one that it expands to.
+func (t *scanTable) scanStep(cursor uint64, fn func(string)) (uint64, int) {
+	small, large := t.buckets, t.next
+	if large != nil && len(large) < len(small) {
+		small, large = large, small
+	}
+	seen := 0
+	visit := func(b []string) {
+		for _, s := range b {
+			fn(s)
+		}
+		seen += len(b)
+	}
+
+	m0 := uint64(len(small) - 1)
+	visit(small[cursor&m0])
+	if large == nil {
+		return nextCursor(cursor, m0), seen
+	}
+	m1 := uint64(len(large) - 1)
+	for {
+		visit(large[cursor&m1])
+		cursor = nextCursor(cursor, m1)
+		if cursor&(m0^m1) == 0 {
+			return cursor, seen
+		}
+	}
+}
+
+// nextCursor increments the reversed bucket index, so the high bits change
+// fastest and buckets that split or merge on a resize are visited together.
+func nextCursor(cursor, mask uint64) uint64 {
+	cursor |= ^mask
+	return bits.Reverse64(bits.Reverse64(cursor) + 1)
+}
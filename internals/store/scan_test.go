@@ -0,0 +1,154 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+)
+
+// TestScanGuarantee checks that keys present for a whole SCAN are returned
+// while other keys are added and removed, growing and shrinking the index.
+func TestScanGuarantee(t *testing.T) {
+	s := NewStore()
+	for i := 0; i < 1000; i++ {
+		s.Set("stable"+strconv.Itoa(i), "v")
+	}
+
+	seen := make(map[string]bool)
+	var cursor uint64
+	for step := 0; ; step++ {
+		var keys []string
+		cursor, keys = s.Scan(cursor, ScanOptions{Count: 10})
+		for _, k := range keys {
+			seen[k] = true
+		}
+		if cursor == 0 {
+			break
+		}
+
+		switch {
+		case step < 20:
+			for i := 0; i < 500; i++ {
+				s.Set("temp"+strconv.Itoa(step*500+i), "v")
+			}
+		case step < 40:
+			for i := 0; i < 500; i++ {
+				s.Del("temp" + strconv.Itoa((step-20)*500+i))
+			}
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		if !seen["stable"+strconv.Itoa(i)] {
+			t.Fatalf("stable%d was not returned", i)
+		}
+	}
+}
+
+func TestScanOptions(t *testing.T) {
+	s := NewStore()
+	s.Set("user:1", "v")
+	s.Set("user:2", "v")
+	s.SAdd("user:set", "m")
+	s.Set("other", "v")
+
+	var found []string
+	var cursor uint64
+	for {
+		var keys []string
+		cursor, keys = s.Scan(cursor, ScanOptions{Match: "user:*", Count: 1, Type: "string"})
+		found = append(found, keys...)
+		if cursor == 0 {
+			break
+		}
+	}
+	if len(found) != 2 {
+		t.Fatalf("scan returned %v, want user:1 and user:2", found)
+	}
+}
+
+func TestSScanLargeSet(t *testing.T) {
+	s := NewStore()
+	for i := 0; i < 1000; i++ {
+		s.SAdd("s", strconv.Itoa(i))
+	}
+	s.SRem("s", "0", "1")
+
+	seen := make(map[string]bool)
+	var cursor uint64
+	for calls := 0; ; calls++ {
+		var members []string
+		cursor, members, _ = s.SScan("s", cursor, ScanOptions{Count: 10})
+		for _, m := range members {
+			seen[m] = true
+		}
+		if cursor == 0 {
+			if calls == 0 {
+				t.Fatal("large set was returned by a single call")
+			}
+			break
+		}
+	}
+	if len(seen) != 998 || seen["0"] {
+		t.Fatalf("scan returned %d members", len(seen))
+	}
+}
+
+// TestScanTableRehashesIncrementally checks that a resize moves buckets a
+// few at a time, and that strings can be found and scanned meanwhile.
+func TestScanTableRehashesIncrementally(t *testing.T) {
+	tbl := newScanTable()
+	for i := 0; !tbl.rehashing() || len(tbl.buckets) < 1024; i++ {
+		tbl.add(strconv.Itoa(i))
+	}
+	size := len(tbl.buckets)
+	tbl.add("one more")
+	if !tbl.rehashing() || tbl.rehashed > rehashEmptyVisits {
+		t.Fatalf("an add rehashed %d of %d buckets", tbl.rehashed, size)
+	}
+
+	// Adding what is already there, found in either table, changes nothing.
+	count := tbl.count
+	for i := 0; i < count-1; i++ {
+		tbl.add(strconv.Itoa(i))
+	}
+	if tbl.count != count {
+		t.Fatalf("count is %d after re-adding, want %d", tbl.count, count)
+	}
+
+	tbl = newScanTable()
+	for i := 0; i < 1000; i++ {
+		tbl.add(strconv.Itoa(i))
+	}
+	seen := make(map[string]bool)
+	var cursor uint64
+	shrunk := false
+	for next := 999; ; {
+		cursor = tbl.scan(cursor, 5, func(s string) { seen[s] = true })
+		if cursor == 0 {
+			break
+		}
+		// Shrink the table from under the scan, keeping the first 50.
+		for i := 0; i < 20 && next >= 50; i++ {
+			tbl.remove(strconv.Itoa(next))
+			next--
+		}
+		shrunk = shrunk || tbl.rehashing()
+	}
+	if !shrunk {
+		t.Fatal("the table never shrank during the scan")
+	}
+	for i := 0; i < 50; i++ {
+		if !seen[strconv.Itoa(i)] {
+			t.Fatalf("%d was not returned by a scan during rehashing", i)
+		}
+		tbl.remove(strconv.Itoa(i))
+	}
+	if tbl.count != 0 {
+		t.Fatalf("count is %d after removing everything", tbl.count)
+	}
+	for _, b := range append(tbl.buckets, tbl.next...) {
+		if len(b) > 0 {
+			t.Fatalf("bucket still holds %v", b)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package store
+
+import (
+	"maps"
+	"math/rand"
+)
+
+func (s *Store) SAdd(key string, members ...string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeSet)
+	if err != nil {
+		return 0, err
+	}
+	if v == nil {
+		v = &value{typ: TypeSet, set: make(map[string]struct{})}
+		s.add(key, v)
+	}
+
+	added := 0
+	for _, m := range members {
+		if v.addMember(m) {
+			added++
+		}
+	}
+	if added > 0 {
+		s.modified(key)
+	}
+	return added, nil
+}
+
+func (s *Store) SRem(key string, members ...string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, m := range members {
+		if v.removeMember(m) {
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.removeIfEmpty(key, v)
+		s.modified(key)
+	}
+	return removed, nil
+}
+
+func (s *Store) SMembers(key string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeSet)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return setMembers(v.set), nil
+}
+
+func (s *Store) SIsMember(key, member string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeSet)
+	if err != nil || v == nil {
+		return false, err
+	}
+	_, ok := v.set[member]
+	return ok, nil
+}
+
+func (s *Store) SMIsMember(key string, members ...string) ([]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeSet)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]bool, len(members))
+	if v == nil {
+		return result, nil
+	}
+	for i, m := range members {
+		_, result[i] = v.set[m]
+	}
+	return result, nil
+}
+
+func (s *Store) SCard(key string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return len(v.set), nil
+}
+
+// SPop removes and returns up to count random members.
+func (s *Store) SPop(key string, count int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeSet)
+	if err != nil || v == nil || count <= 0 {
+		return nil, err
+	}
+
+	popped := randomMembers(v, count)
+	for _, m := range popped {
+		v.removeMember(m)
+	}
+	s.removeIfEmpty(key, v)
+	s.modified(key)
+	return popped, nil
+}
+
+// SRandMember returns up to count distinct random members, or exactly -count
+// members that may repeat when count is negative.
+func (s *Store) SRandMember(key string, count int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeSet)
+	if err != nil || v == nil || count == 0 {
+		return nil, err
+	}
+
+	if count > 0 {
+		return randomMembers(v, count), nil
+	}
+	if v.index != nil {
+		members, _ := v.index.sample(-count, false, nil)
+		return members, nil
+	}
+
+	// Without an index the set is small enough to copy.
+	members := setMembers(v.set)
+	var result []string
+	for range -count {
+		result = append(result, members[rand.Intn(len(members))])
+	}
+	return result, nil
+}
+
+func (s *Store) SMove(src, dst, member string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, err := s.lookupWriteType(src, TypeSet)
+	if err != nil {
+		return false, err
+	}
+	to, err := s.lookupWriteType(dst, TypeSet)
+	if err != nil {
+		return false, err
+	}
+	if from == nil {
+		return false, nil
+	}
+	if _, exists := from.set[member]; !exists {
+		return false, nil
+	}
+	if src == dst {
+		return true, nil
+	}
+
+	from.removeMember(member)
+	s.removeIfEmpty(src, from)
+	if to == nil {
+		to = &value{typ: TypeSet, set: make(map[string]struct{})}
+		s.add(dst, to)
+	}
+	to.addMember(member)
+	s.modified(src)
+	s.modified(dst)
+	return true, nil
+}
+
+func (s *Store) SInter(keys ...string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, err := s.setAlgebra(keys, setInter)
+	if err != nil {
+		return nil, err
+	}
+	return setMembers(result), nil
+}
+
+func (s *Store) SUnion(keys ...string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, err := s.setAlgebra(keys, setUnion)
+	if err != nil {
+		return nil, err
+	}
+	return setMembers(result), nil
+}
+
+func (s *Store) SDiff(keys ...string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, err := s.setAlgebra(keys, setDiff)
+	if err != nil {
+		return nil, err
+	}
+	return setMembers(result), nil
+}
+
+func (s *Store) SInterStore(dst string, keys ...string) (int, error) {
+	return s.setAlgebraStore(dst, keys, setInter)
+}
+
+func (s *Store) SUnionStore(dst string, keys ...string) (int, error) {
+	return s.setAlgebraStore(dst, keys, setUnion)
+}
+
+func (s *Store) SDiffStore(dst string, keys ...string) (int, error) {
+	return s.setAlgebraStore(dst, keys, setDiff)
+}
+
+type setOp int
+
+const (
+	setInter setOp = iota
+	setUnion
+	setDiff
+)
+
+// setAlgebra combines the sets at keys, missing keys count as empty sets.
+// The caller must hold at least a read lock.
+func (s *Store) setAlgebra(keys []string, op setOp) (map[string]struct{}, error) {
+	sets := make([]map[string]struct{}, len(keys))
+	for i, key := range keys {
+		v, err := s.lookupType(key, TypeSet)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			sets[i] = v.set
+		}
+	}
+
+	result := make(map[string]struct{})
+	switch op {
+	case setInter:
+		for m := range sets[0] {
+			inAll := true
+			for _, other := range sets[1:] {
+				if _, ok := other[m]; !ok {
+					inAll = false
+					break
+				}
+			}
+			if inAll {
+				result[m] = struct{}{}
+			}
+		}
+	case setUnion:
+		for _, set := range sets {
+			for m := range set {
+				result[m] = struct{}{}
+			}
+		}
+	case setDiff:
+		for m := range sets[0] {
+			result[m] = struct{}{}
+		}
+		for _, other := range sets[1:] {
+			for m := range other {
+				delete(result, m)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *Store) setAlgebraStore(dst string, keys []string, op setOp) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.setAlgebra(keys, op)
+	if err != nil {
+		return 0, err
+	}
+
+	s.remove(dst)
+	if len(result) > 0 {
+		s.add(dst, &value{typ: TypeSet, set: result, index: newIndex(len(result), maps.Keys(result))})
+	}
+	s.modified(dst)
+	return len(result), nil
+}
+
+// addMember adds m to a set, reporting whether it is new.
+func (v *value) addMember(m string) bool {
+	if _, exists := v.set[m]; exists {
+		return false
+	}
+	v.set[m] = struct{}{}
+	indexAdd(&v.index, m, len(v.set), maps.Keys(v.set))
+	return true
+}
+
+// removeMember removes m from a set, reporting whether it was there.
+func (v *value) removeMember(m string) bool {
+	if _, exists := v.set[m]; !exists {
+		return false
+	}
+	delete(v.set, m)
+	indexRemove(v.index, m)
+	return true
+}
+
+func setMembers(set map[string]struct{}) []string {
+	members := make([]string, 0, len(set))
+	for m := range set {
+		members = append(members, m)
+	}
+	return members
+}
+
+// randomMembers picks up to count distinct members of a set at random. A
+// count well below the size is drawn from the set's index, as Redis does,
+// so it costs O(count) rather than a copy of the whole set.
+func randomMembers(v *value, count int) []string {
+	if count >= len(v.set) {
+		return setMembers(v.set)
+	}
+	if v.index != nil && count*3 <= len(v.set) {
+		if members, ok := v.index.sample(count, true, nil); ok {
+			return members
+		}
+	}
+	return pickRandom(setMembers(v.set), count)
+}
+
+// pickRandom moves count random elements of s to its front with a partial
+// Fisher–Yates shuffle and returns them.
+func pickRandom(s []string, count int) []string {
+	for i := range count {
+		j := i + rand.Intn(len(s)-i)
+		s[i], s[j] = s[j], s[i]
+	}
+	return s[:count]
+}
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func newSet(t *testing.T, key string, members ...string) *Store {
+	t.Helper()
+	s := NewStore()
+	if _, err := s.SAdd(key, members...); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func sortedMembers(t *testing.T, s *Store, key string) []string {
+	t.Helper()
+	members, err := s.SMembers(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(members)
+	return members
+}
+
+func TestSPop(t *testing.T) {
+	s := newSet(t, "s", "a", "b", "c")
+
+	popped, err := s.SPop("s", 2)
+	if err != nil || len(popped) != 2 || popped[0] == popped[1] {
+		t.Fatalf("SPop = %v, %v", popped, err)
+	}
+	for _, m := range popped {
+		if ok, _ := s.SIsMember("s", m); ok {
+			t.Fatalf("popped member %q is still in the set", m)
+		}
+	}
+
+	if popped, _ := s.SPop("s", 10); len(popped) != 1 {
+		t.Fatalf("SPop past the size returned %v", popped)
+	}
+	if s.Exists("s") {
+		t.Fatal("empty set was not removed")
+	}
+	if popped, err := s.SPop("s", 1); err != nil || popped != nil {
+		t.Fatalf("SPop on a missing key = %v, %v", popped, err)
+	}
+}
+
+func TestSRandMemberCounts(t *testing.T) {
+	s := newSet(t, "s", "a", "b", "c")
+
+	members, _ := s.SRandMember("s", 10)
+	slices.Sort(members)
+	if !slices.Equal(members, []string{"a", "b", "c"}) {
+		t.Fatalf("positive count returned %v", members)
+	}
+
+	members, _ = s.SRandMember("s", -10)
+	if len(members) != 10 {
+		t.Fatalf("negative count returned %d members, want 10", len(members))
+	}
+	for _, m := range members {
+		if ok, _ := s.SIsMember("s", m); !ok {
+			t.Fatalf("returned %q, which is not a member", m)
+		}
+	}
+	if n, _ := s.SCard("s"); n != 3 {
+		t.Fatalf("SRandMember changed the set, SCard = %d", n)
+	}
+}
+
+func TestSMove(t *testing.T) {
+	s := newSet(t, "src", "a", "b")
+	s.Set("str", "x")
+
+	if moved, err := s.SMove("src", "dst", "a"); err != nil || !moved {
+		t.Fatalf("SMove = %v, %v", moved, err)
+	}
+	if moved, _ := s.SMove("src", "dst", "missing"); moved {
+		t.Fatal("SMove moved a member that does not exist")
+	}
+	if got := sortedMembers(t, s, "dst"); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("destination holds %v", got)
+	}
+
+	s.SMove("src", "dst", "b")
+	if s.Exists("src") {
+		t.Fatal("empty source set was not removed")
+	}
+	if _, err := s.SMove("dst", "str", "a"); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("SMove to a string returned %v, want ErrWrongType", err)
+	}
+	if ok, _ := s.SIsMember("dst", "a"); !ok {
+		t.Fatal("a failed SMove removed the member")
+	}
+}
+
+func TestSetAlgebraStore(t *testing.T) {
+	s := newSet(t, "a", "1", "2", "3")
+	s.SAdd("b", "2", "3", "4")
+
+	tests := []struct {
+		name  string
+		store func(dst string, keys ...string) (int, error)
+		want  []string
+	}{
+		{"SInterStore", s.SInterStore, []string{"2", "3"}},
+		{"SUnionStore", s.SUnionStore, []string{"1", "2", "3", "4"}},
+		{"SDiffStore", s.SDiffStore, []string{"1"}},
+	}
+	for _, tt := range tests {
+		if n, err := tt.store("dst", "a", "b"); err != nil || n != len(tt.want) {
+			t.Fatalf("%s = %d, %v", tt.name, n, err)
+		}
+		if got := sortedMembers(t, s, "dst"); !slices.Equal(got, tt.want) {
+			t.Errorf("%s stored %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// An empty result removes the destination, whatever it held before.
+	s.Set("dst", "x")
+	if n, err := s.SInterStore("dst", "a", "missing"); err != nil || n != 0 {
+		t.Fatalf("SInterStore with a missing key = %d, %v", n, err)
+	}
+	if s.Exists("dst") {
+		t.Fatal("empty result left the destination in place")
+	}
+
+	s.Set("str", "x")
+	if _, err := s.SUnionStore("dst", "a", "str"); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("SUnionStore over a string returned %v, want ErrWrongType", err)
+	}
+}
+
+func TestRandomMembersOfIndexedSet(t *testing.T) {
+	members := make([]string, 1000)
+	for i := range members {
+		members[i] = strconv.Itoa(i)
+	}
+	s := newSet(t, "s", members...)
+
+	isDistinctMembers := func(got []string) bool {
+		seen := map[string]bool{}
+		for _, m := range got {
+			if ok, _ := s.SIsMember("s", m); !ok || seen[m] {
+				return false
+			}
+			seen[m] = true
+		}
+		return true
+	}
+
+	// Small counts are drawn from the index, large ones from a copy.
+	for _, count := range []int{1, 10, 333, 500} {
+		got, err := s.SRandMember("s", count)
+		if err != nil || len(got) != count || !isDistinctMembers(got) {
+			t.Fatalf("SRandMember(%d) = %d members, %v", count, len(got), err)
+		}
+	}
+	if got, _ := s.SRandMember("s", -2000); len(got) != 2000 {
+		t.Fatalf("SRandMember(-2000) returned %d members", len(got))
+	}
+
+	seen := map[string]bool{}
+	for range 200 {
+		got, _ := s.SRandMember("s", 1)
+		seen[got[0]] = true
+	}
+	if len(seen) < 100 {
+		t.Fatalf("200 draws returned only %d distinct members", len(seen))
+	}
+
+	popped, _ := s.SPop("s", 10)
+	if len(popped) != 10 {
+		t.Fatalf("SPop returned %v", popped)
+	}
+	for _, m := range popped {
+		if ok, _ := s.SIsMember("s", m); ok {
+			t.Fatalf("popped member %q is still in the set", m)
+		}
+	}
+	if n, _ := s.SCard("s"); n != 990 {
+		t.Fatalf("SCard after SPop = %d, want 990", n)
+	}
+}
+
+func BenchmarkSRandMemberOfMillion(b *testing.B) {
+	s := NewStore()
+	for i := range 1_000_000 {
+		s.SAdd("s", strconv.Itoa(i))
+	}
+	b.ResetTimer()
+	for range b.N {
+		s.SRandMember("s", 1)
+	}
+}
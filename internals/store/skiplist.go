@@ -0,0 +1,338 @@
+package store
+
+import (
+	"math/rand"
+)
+
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 0.25
+)
+
+// skiplist keeps sorted-set members ordered by score, then by member for
+// equal scores. Every forward link records how many nodes it skips so ranks
+// can be computed in O(log n).
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	level    []skiplistLevel
+}
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{level: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// before reports whether n sorts before (score, member).
+func (n *skiplistNode) before(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// insert adds a member that must not already be in the list.
+func (zsl *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		if i < zsl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > zsl.level {
+		for i := zsl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = zsl.header
+			update[i].level[i].span = zsl.length
+		}
+		zsl.level = level
+	}
+
+	x = &skiplistNode{member: member, score: score, level: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < zsl.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != zsl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		zsl.tail = x
+	}
+	zsl.length++
+	return x
+}
+
+func (zsl *skiplist) delete(score float64, member string) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.level[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+	zsl.deleteNode(x, update[:zsl.level])
+	return true
+}
+
+func (zsl *skiplist) deleteNode(x *skiplistNode, update []*skiplistNode) {
+	for i := 0; i < zsl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		zsl.tail = x.backward
+	}
+	for zsl.level > 1 && zsl.header.level[zsl.level-1].forward == nil {
+		zsl.level--
+	}
+	zsl.length--
+}
+
+// rank returns the 1-based rank of (score, member), or 0 if it is missing.
+func (zsl *skiplist) rank(score float64, member string) int {
+	rank := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.before(score, member) ||
+				(x.level[i].forward.score == score && x.level[i].forward.member == member)) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+		if x != zsl.header && x.score == score && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank returns the node at the 1-based rank, or nil if out of range.
+func (zsl *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+func (zsl *skiplist) firstInRange(r ScoreRange) *skiplistNode {
+	if !zsl.isInRange(r) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.aboveMin(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if x == nil || !r.belowMax(x.score) {
+		return nil
+	}
+	return x
+}
+
+func (zsl *skiplist) lastInRange(r ScoreRange) *skiplistNode {
+	if !zsl.isInRange(r) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.belowMax(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+	if x == zsl.header || !r.aboveMin(x.score) {
+		return nil
+	}
+	return x
+}
+
+// isInRange reports whether any part of the list can fall inside r.
+func (zsl *skiplist) isInRange(r ScoreRange) bool {
+	if r.Min > r.Max || (r.Min == r.Max && (r.MinExclusive || r.MaxExclusive)) {
+		return false
+	}
+	if zsl.tail == nil || !r.aboveMin(zsl.tail.score) {
+		return false
+	}
+	first := zsl.header.level[0].forward
+	return first != nil && r.belowMax(first.score)
+}
+
+func (zsl *skiplist) firstInLexRange(r LexRange) *skiplistNode {
+	if !zsl.isInLexRange(r) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.aboveMin(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if x == nil || !r.belowMax(x.member) {
+		return nil
+	}
+	return x
+}
+
+func (zsl *skiplist) lastInLexRange(r LexRange) *skiplistNode {
+	if !zsl.isInLexRange(r) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.belowMax(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+	if x == zsl.header || !r.aboveMin(x.member) {
+		return nil
+	}
+	return x
+}
+
+func (zsl *skiplist) isInLexRange(r LexRange) bool {
+	if r.empty() {
+		return false
+	}
+	if zsl.tail == nil || !r.aboveMin(zsl.tail.member) {
+		return false
+	}
+	first := zsl.header.level[0].forward
+	return first != nil && r.belowMax(first.member)
+}
+
+// ScoreRange is an interval of scores, either end may be exclusive.
+type ScoreRange struct {
+	Min, Max                   float64
+	MinExclusive, MaxExclusive bool
+}
+
+func (r ScoreRange) aboveMin(score float64) bool {
+	if r.MinExclusive {
+		return score > r.Min
+	}
+	return score >= r.Min
+}
+
+func (r ScoreRange) belowMax(score float64) bool {
+	if r.MaxExclusive {
+		return score < r.Max
+	}
+	return score <= r.Max
+}
+
+// LexBound is one end of a LexRange. Inf is -1 for "-" and +1 for "+",
+// which sort before and after every member respectively.
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	Inf       int
+}
+
+// LexRange is an interval of members, only meaningful when every member of
+// the sorted set has the same score.
+type LexRange struct {
+	Min, Max LexBound
+}
+
+func (r LexRange) aboveMin(member string) bool {
+	switch r.Min.Inf {
+	case -1:
+		return true
+	case 1:
+		return false
+	}
+	if r.Min.Exclusive {
+		return member > r.Min.Value
+	}
+	return member >= r.Min.Value
+}
+
+func (r LexRange) belowMax(member string) bool {
+	switch r.Max.Inf {
+	case 1:
+		return true
+	case -1:
+		return false
+	}
+	if r.Max.Exclusive {
+		return member < r.Max.Value
+	}
+	return member <= r.Max.Value
+}
+
+func (r LexRange) empty() bool {
+	if r.Min.Inf == 1 || r.Max.Inf == -1 {
+		return true
+	}
+	if r.Min.Inf == -1 || r.Max.Inf == 1 {
+		return false
+	}
+	if r.Min.Value > r.Max.Value {
+		return true
+	}
+	return r.Min.Value == r.Max.Value && (r.Min.Exclusive || r.Max.Exclusive)
+}
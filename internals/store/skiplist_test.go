@@ -0,0 +1,113 @@
+package store
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestSkiplistMatchesSortedSlice(t *testing.T) {
+	zsl := newSkiplist()
+	scores := make(map[string]float64)
+
+	for i := 0; i < 2000; i++ {
+		member := fmt.Sprintf("m%d", rand.Intn(500))
+		if score, exists := scores[member]; exists && rand.Intn(3) == 0 {
+			if !zsl.delete(score, member) {
+				t.Fatalf("delete(%v, %q) reported missing member", score, member)
+			}
+			delete(scores, member)
+			continue
+		} else if exists {
+			zsl.delete(score, member)
+		}
+		score := float64(rand.Intn(50))
+		zsl.insert(score, member)
+		scores[member] = score
+	}
+
+	want := make([]ZMember, 0, len(scores))
+	for m, s := range scores {
+		want = append(want, ZMember{Member: m, Score: s})
+	}
+	sort.Slice(want, func(i, j int) bool {
+		if want[i].Score != want[j].Score {
+			return want[i].Score < want[j].Score
+		}
+		return want[i].Member < want[j].Member
+	})
+
+	if zsl.length != len(want) {
+		t.Fatalf("expected length %d, got %d", len(want), zsl.length)
+	}
+
+	x := zsl.tail
+	for i := len(want) - 1; i >= 0; i-- {
+		if x == nil || x.member != want[i].Member {
+			t.Fatalf("backward walk diverged at %d", i)
+		}
+		x = x.backward
+	}
+
+	for i, m := range want {
+		if rank := zsl.rank(m.Score, m.Member); rank != i+1 {
+			t.Fatalf("rank(%q) expected %d, got %d", m.Member, i+1, rank)
+		}
+		if n := zsl.byRank(i + 1); n == nil || n.member != m.Member {
+			t.Fatalf("byRank(%d) expected %q", i+1, m.Member)
+		}
+	}
+
+	r := ScoreRange{Min: 10, Max: 20, MinExclusive: true}
+	first, last := zsl.firstInRange(r), zsl.lastInRange(r)
+	for _, m := range want {
+		if r.aboveMin(m.Score) && r.belowMax(m.Score) {
+			if first == nil || first.member != m.Member {
+				t.Fatalf("firstInRange expected %q", m.Member)
+			}
+			break
+		}
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		if r.aboveMin(want[i].Score) && r.belowMax(want[i].Score) {
+			if last == nil || last.member != want[i].Member {
+				t.Fatalf("lastInRange expected %q", want[i].Member)
+			}
+			break
+		}
+	}
+}
+
+func TestLexRange(t *testing.T) {
+	z := newZSet()
+	for _, m := range []string{"a", "b", "c", "d", "e"} {
+		z.set(m, 0)
+	}
+
+	tests := []struct {
+		name string
+		r    LexRange
+		rev  bool
+		want []string
+	}{
+		{"all", LexRange{Min: LexBound{Inf: -1}, Max: LexBound{Inf: 1}}, false, []string{"a", "b", "c", "d", "e"}},
+		{"inclusive", LexRange{Min: LexBound{Value: "b"}, Max: LexBound{Value: "d"}}, false, []string{"b", "c", "d"}},
+		{"exclusive", LexRange{Min: LexBound{Value: "b", Exclusive: true}, Max: LexBound{Value: "d", Exclusive: true}}, false, []string{"c"}},
+		{"reverse", LexRange{Min: LexBound{Value: "c"}, Max: LexBound{Inf: 1}}, true, []string{"e", "d", "c"}},
+		{"empty", LexRange{Min: LexBound{Inf: 1}, Max: LexBound{Inf: -1}}, false, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := z.rangeOf(ZRangeSpec{By: ZRangeByLex, Lex: tt.r, Rev: tt.rev, Count: -1})
+			members := make([]string, len(got))
+			for i, m := range got {
+				members[i] = m.Member
+			}
+			if fmt.Sprint(members) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, members)
+			}
+		})
+	}
+}
@@ -1,63 +1,214 @@
 package store
 
 import (
-	"fmt"
-	"log"
+	"errors"
+	"math/rand"
 	"sync"
 	"time"
 )
 
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+type Type byte
+
+const (
+	TypeString Type = iota
+	TypeList
+	TypeHash
+	TypeSet
+	TypeZSet
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeList:
+		return "list"
+	case TypeHash:
+		return "hash"
+	case TypeSet:
+		return "set"
+	case TypeZSet:
+		return "zset"
+	default:
+		return "none"
+	}
+}
+
+// value is the single typed value a key holds; only the field matching typ
+// is used.
+type value struct {
+	typ Type
+	str string
+	// num holds a string that is an integer once it has been used as a
+	// counter, so increments don't reparse str every time. str is unused
+	// while isInt is set.
+	num   int64
+	isInt bool
+	list  *quicklist
+	hash  map[string]string
+	set   map[string]struct{}
+	zset  *zset
+	// index is the scan index of a large hash or set.
+	index *scanTable
+	// fieldExpiries holds the expiry of every hash field that has one.
+	fieldExpiries map[string]time.Time
+}
+
 type Store struct {
-	data     map[string]string
-	hashes   map[string]map[string]string
-	lists    map[string][]string
-	waiters  map[string][]chan [2]string
-	expiries map[string]time.Time
-	mu       sync.RWMutex
+	keys     map[string]*value
+	index    *scanTable
+	blocking map[string]int
+	ready    map[string]struct{}
+	expiries *expiryTable
+	// hashExpiries holds the hashes with field expiries, each with a time
+	// no later than its earliest field expiry, so neither the cleaner nor
+	// writers have to look at every field.
+	hashExpiries *expiryTable
+	watches      map[string]*watch
+	dirty        uint64
+	expireStats  ExpireStats
+	// sampler picks the keys the active expire cycle looks at. It is only
+	// used with the write lock held.
+	sampler *rand.Rand
+	mu      sync.RWMutex
+}
+
+// watch tracks a key under WATCH: version is bumped on every modification
+// so a transaction can tell whether the key changed since it was watched.
+type watch struct {
+	version  uint64
+	watchers int
 }
 
 func NewStore() *Store {
 	return &Store{
-		data:     make(map[string]string),
-		hashes:   make(map[string]map[string]string),
-		lists:    make(map[string][]string),
-		waiters:  make(map[string][]chan [2]string),
-		expiries: make(map[string]time.Time),
+		keys:         make(map[string]*value),
+		index:        newScanTable(),
+		blocking:     make(map[string]int),
+		ready:        make(map[string]struct{}),
+		expiries:     newExpiryTable(),
+		hashExpiries: newExpiryTable(),
+		watches:      make(map[string]*watch),
+		sampler:      rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
-func (s *Store) Set(key, value string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.data[key] = value
+// lookup returns the live value for key, treating expired keys as missing.
+// The caller must hold at least a read lock.
+func (s *Store) lookup(key string) *value {
+	v, ok := s.keys[key]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if exp, hasExpiry := s.expiries.get(key); hasExpiry && now.After(exp) {
+		return nil
+	}
+	if v.typ == TypeHash && v.expiredHash(now) {
+		return nil
+	}
+	return v
 }
 
-func (s *Store) Get(key string) (string, bool) {
-	s.mu.RLock()
-	exp, hasExpiry := s.expiries[key]
-	isExpired := hasExpiry && time.Now().After(exp)
-	s.mu.RUnlock()
+// lookupWrite is lookup for callers holding the write lock, an expired key
+// or hash field is removed so the write starts from an empty slot.
+func (s *Store) lookupWrite(key string) *value {
+	v := s.lookup(key)
+	if v == nil {
+		if _, exists := s.keys[key]; exists {
+			s.expireStats.ExpiredKeys++
+		}
+		s.remove(key)
+		return nil
+	}
+	if at, ok := s.hashExpiries.get(key); ok && time.Now().After(at) && !s.expireFields(key, v, time.Now()) {
+		return nil
+	}
+	return v
+}
 
-	if isExpired {
-		s.mu.Lock()
-		delete(s.data, key)
-		delete(s.expiries, key)
-		s.mu.Unlock()
-		return "", false
+// lookupType returns the value for key if it holds typ, nil if the key does
+// not exist, and ErrWrongType otherwise.
+func (s *Store) lookupType(key string, typ Type) (*value, error) {
+	v := s.lookup(key)
+	if v != nil && v.typ != typ {
+		return nil, ErrWrongType
 	}
+	return v, nil
+}
 
-	s.mu.RLock()
-	val, ok := s.data[key]
-	s.mu.RUnlock()
-	return val, ok
+// lookupWriteType is lookupType for callers holding the write lock.
+func (s *Store) lookupWriteType(key string, typ Type) (*value, error) {
+	v := s.lookupWrite(key)
+	if v != nil && v.typ != typ {
+		return nil, ErrWrongType
+	}
+	return v, nil
+}
+
+// add stores v at key, replacing whatever was there. The caller must hold
+// the write lock.
+func (s *Store) add(key string, v *value) {
+	if _, exists := s.keys[key]; !exists {
+		s.index.add(key)
+	}
+	s.keys[key] = v
+}
+
+func (s *Store) remove(key string) {
+	if _, exists := s.keys[key]; exists {
+		s.touch(key)
+		s.index.remove(key)
+	}
+	delete(s.keys, key)
+	s.expiries.delete(key)
+	s.hashExpiries.delete(key)
+}
+
+// modified records a write to key. The caller must hold the write lock.
+func (s *Store) modified(key string) {
+	s.dirty++
+	s.touch(key)
+}
+
+func (s *Store) touch(key string) {
+	if w, ok := s.watches[key]; ok {
+		w.version++
+	}
+}
+
+// removeIfEmpty deletes a container once its last element is gone, so an
+// empty container never lingers as an existing key.
+func (s *Store) removeIfEmpty(key string, v *value) {
+	switch v.typ {
+	case TypeList:
+		if v.list.len() == 0 {
+			s.remove(key)
+		}
+	case TypeHash:
+		if len(v.hash) == 0 {
+			s.remove(key)
+		}
+	case TypeSet:
+		if len(v.set) == 0 {
+			s.remove(key)
+		}
+	case TypeZSet:
+		if len(v.zset.dict) == 0 {
+			s.remove(key)
+		}
+	}
 }
 
 func (s *Store) Del(key string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.data[key]; exists {
-		delete(s.data, key)
+	if v := s.lookupWrite(key); v != nil {
+		s.remove(key)
+		s.modified(key)
 		return true
 	}
 	return false
@@ -67,151 +218,143 @@ func (s *Store) Exists(key string) bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	_, exists := s.data[key]
-	return exists
+	return s.lookup(key) != nil
 }
 
-func (s *Store) Expire(key string, seconds int) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Type reports the type of the value at key, ok is false if it does not exist.
+func (s *Store) Type(key string) (Type, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	if _, exists := s.data[key]; !exists {
-		return false
+	v := s.lookup(key)
+	if v == nil {
+		return 0, false
 	}
-	s.expiries[key] = time.Now().Add(time.Duration(seconds) * time.Second)
-	return true
+	return v.typ, true
 }
 
-func (s *Store) HSet(key string, fields map[string]string) {
+// ExpireAt sets the expiry of key to at when cond allows it, deleting the
+// key if at has already passed. It reports whether the key was changed.
+func (s *Store) ExpireAt(key string, at time.Time, cond ExpireCondition) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.hashes[key]; !exists {
-		s.hashes[key] = make(map[string]string)
+	if v := s.lookupWrite(key); v == nil {
+		return false
 	}
-	for field, value := range fields {
-		s.hashes[key][field] = value
+	cur, hasExpiry := s.expiries.get(key)
+	if !cond.allows(cur, hasExpiry, at) {
+		return false
 	}
-
-}
-
-func (s *Store) HGet(key, field string) (string, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	if hash, exists := s.hashes[key]; exists {
-		val, ok := hash[field]
-		return val, ok
+	if at.After(time.Now()) {
+		s.expiries.set(key, at)
+	} else {
+		s.remove(key)
 	}
-	return "", false
+	s.modified(key)
+	return true
 }
 
-func (s *Store) HGetAll(key string) map[string]string {
+// ExpireTime returns the expiry of key, the zero time if it has none. ok is
+// false if the key does not exist.
+func (s *Store) ExpireTime(key string) (at time.Time, ok bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if hash, exists := s.hashes[key]; exists {
-		copy := make(map[string]string)
-		for k, v := range hash {
-			copy[k] = v
-		}
-		return copy
+	if v := s.lookup(key); v == nil {
+		return time.Time{}, false
 	}
-	return nil
+	at, _ = s.expiries.get(key)
+	return at, true
 }
 
-func (s *Store) LPush(key string, values ...string) int {
+// Persist removes the expiry of key, reporting whether it had one.
+func (s *Store) Persist(key string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	list := s.lists[key]
-	list = append(reverse(values), list...)
-	s.lists[key] = list
-
-	for len(list) > 0 && len(s.waiters[key]) > 0 {
-		waitCh := s.waiters[key][0]
-		s.waiters[key] = s.waiters[key][1:]
-
-		val := list[0]
-		list = list[1:]
-		waitCh <- [2]string{key, val}
+	if v := s.lookupWrite(key); v == nil {
+		return false
 	}
-
-	log.Printf("LPUSH array %v", list)
-
-	return len(list)
+	if _, hasExpiry := s.expiries.get(key); !hasExpiry {
+		return false
+	}
+	s.expiries.delete(key)
+	s.modified(key)
+	return true
 }
 
-func (s *Store) RPush(key string, values ...string) int {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// ExpireCondition restricts when an expiry may be set, as the NX, XX, GT and
+// LT options of EXPIRE do. Conditions may be combined, e.g. ExpireXX|ExpireGT.
+type ExpireCondition int
 
-	list := s.lists[key]
-	list = append(list, values...)
-	s.lists[key] = list
+const (
+	ExpireNX ExpireCondition = 1 << iota
+	ExpireXX
+	ExpireGT
+	ExpireLT
 
-	log.Printf("RPUSH array %v", list)
+	ExpireAlways ExpireCondition = 0
+)
 
-	return len((list))
+// allows reports whether at may replace the current expiry cur, hasExpiry
+// being false if there is none. No expiry counts as an infinite one.
+func (c ExpireCondition) allows(cur time.Time, hasExpiry bool, at time.Time) bool {
+	switch {
+	case c&ExpireNX != 0 && hasExpiry:
+		return false
+	case c&ExpireXX != 0 && !hasExpiry:
+		return false
+	case c&ExpireGT != 0 && (!hasExpiry || !at.After(cur)):
+		return false
+	case c&ExpireLT != 0 && hasExpiry && !at.Before(cur):
+		return false
+	}
+	return true
+}
 
+// Dirty returns a counter bumped by every write that changed the store, so
+// callers can tell whether a command actually modified anything.
+func (s *Store) Dirty() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dirty
 }
 
-func (s *Store) LPop(key string) (string, bool) {
+// Watch starts tracking modifications to key and returns its current
+// version. Every Watch must be paired with an Unwatch.
+func (s *Store) Watch(key string) uint64 {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	list := s.lists[key]
-	if len(list) == 0 {
-		return "", false
+	w, ok := s.watches[key]
+	if !ok {
+		w = &watch{}
+		s.watches[key] = w
 	}
-	val := list[0]
-	s.lists[key] = list[1:]
-	return val, true
+	w.watchers++
+	return w.version
 }
 
-func (s *Store) RPop(key string) (string, bool) {
+func (s *Store) Unwatch(key string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	list := s.lists[key]
-	if len(list) == 0 {
-		return "", false
-	}
-	lastInd := len(list) - 1
-	val := list[lastInd]
-	s.lists[key] = list[:lastInd]
-	return val, true
-}
-
-func reverse(s []string) []string {
-	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
-		s[i], s[j] = s[j], s[i]
+	if w, ok := s.watches[key]; ok {
+		w.watchers--
+		if w.watchers <= 0 {
+			delete(s.watches, key)
+		}
 	}
-
-	return s
 }
 
-func (s *Store) RegisterWaiter(key string, ch chan [2]string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// WatchVersion returns the current version of a watched key.
+func (s *Store) WatchVersion(key string) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	s.waiters[key] = append(s.waiters[key], ch)
-}
-
-func (s *Store) StartCleaner(interval time.Duration) {
-	go func() {
-		for {
-			time.Sleep(interval)
-			s.mu.Lock()
-			now := time.Now()
-			for key, exp := range s.expiries {
-				if now.After(exp) {
-					delete(s.data, key)
-					delete(s.expiries, key)
-					fmt.Println("[cleaner] Deleted expired key:", key)
-				}
-			}
-			s.mu.Unlock()
-		}
-	}()
+	if w, ok := s.watches[key]; ok {
+		return w.version
+	}
+	return 0
 }
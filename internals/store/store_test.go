@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpireAtConditions(t *testing.T) {
+	s := NewStore()
+	s.Set("k", "v")
+	soon := time.Now().Add(time.Minute)
+	later := soon.Add(time.Minute)
+
+	if s.ExpireAt("k", soon, ExpireXX) || s.ExpireAt("k", soon, ExpireGT) {
+		t.Fatal("XX or GT set an expiry on a key without one")
+	}
+	if !s.ExpireAt("k", later, ExpireLT) {
+		t.Fatal("LT did not set an expiry on a key without one")
+	}
+	if s.ExpireAt("k", later, ExpireNX) || s.ExpireAt("k", later, ExpireXX|ExpireGT) {
+		t.Fatal("NX or XX GT with the same time replaced the expiry")
+	}
+	if !s.ExpireAt("k", soon, ExpireXX|ExpireLT) {
+		t.Fatal("XX LT did not shorten the expiry")
+	}
+	if at, _ := s.ExpireTime("k"); !at.Equal(soon) {
+		t.Fatalf("expiry is %v, want %v", at, soon)
+	}
+
+	if !s.Persist("k") || s.Persist("k") {
+		t.Fatal("Persist should succeed exactly once")
+	}
+	if !s.ExpireAt("k", time.Now().Add(-time.Second), ExpireAlways) || s.Exists("k") {
+		t.Fatal("an expiry in the past did not delete the key")
+	}
+}
+
+func TestSetClearsExpiry(t *testing.T) {
+	s := NewStore()
+	s.Set("k", "v")
+	s.ExpireAt("k", time.Now().Add(time.Minute), ExpireAlways)
+	s.Set("k", "w")
+	if at, ok := s.ExpireTime("k"); !ok || !at.IsZero() {
+		t.Fatalf("expiry after Set is %v, want none", at)
+	}
+}
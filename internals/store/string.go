@@ -0,0 +1,397 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// MaxStringLength is the largest string SETRANGE and APPEND may produce.
+const MaxStringLength = 512 << 20
+
+var (
+	ErrStringTooLong = errors.New("string exceeds maximum allowed size (proto-max-bulk-len)")
+	ErrNotInteger    = errors.New("value is not an integer or out of range")
+	ErrNotFloat      = errors.New("value is not a valid float")
+	ErrOverflow      = errors.New("increment or decrement would overflow")
+	ErrNaNOrInfinity = errors.New("increment would produce NaN or Infinity")
+)
+
+func (v *value) stringValue() string {
+	if v.isInt {
+		return strconv.FormatInt(v.num, 10)
+	}
+	return v.str
+}
+
+func (v *value) setStr(str string) {
+	v.str, v.num, v.isInt = str, 0, false
+}
+
+// intValue returns the string as an integer, which it only is if it is the
+// canonical decimal form of one: no sign other than '-', no leading zeros.
+func (v *value) intValue() (int64, bool) {
+	if v.isInt {
+		return v.num, true
+	}
+	n, err := strconv.ParseInt(v.str, 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != v.str {
+		return 0, false
+	}
+	return n, true
+}
+
+// setString replaces whatever key holds with a string. The TTL is cleared
+// unless keepTTL is set. The caller must hold the write lock.
+func (s *Store) setString(key, val string, keepTTL bool) {
+	s.add(key, &value{typ: TypeString, str: val})
+	s.hashExpiries.delete(key)
+	if !keepTTL {
+		s.expiries.delete(key)
+	}
+	s.modified(key)
+}
+
+func (s *Store) Set(key, val string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setString(key, val, false)
+}
+
+// SetOptions control SetWith. NX and XX only set the key if it does not or
+// does already exist; ExpireAt, unless zero, sets a TTL, and KeepTTL keeps
+// the existing one instead of clearing it. Get returns the old value, which
+// must then be a string.
+type SetOptions struct {
+	NX, XX   bool
+	KeepTTL  bool
+	ExpireAt time.Time
+	Get      bool
+}
+
+// SetWith is SET with all its options. It returns the previous value when
+// opts.Get is set and whether the new value was written.
+func (s *Store) SetWith(key, val string, opts SetOptions) (old string, hadOld, written bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := s.lookupWrite(key)
+	if opts.Get && v != nil {
+		if v.typ != TypeString {
+			return "", false, false, ErrWrongType
+		}
+		old, hadOld = v.stringValue(), true
+	}
+	if (opts.NX && v != nil) || (opts.XX && v == nil) {
+		return old, hadOld, false, nil
+	}
+
+	s.setString(key, val, opts.KeepTTL)
+	if !opts.ExpireAt.IsZero() {
+		s.expiries.set(key, opts.ExpireAt)
+	}
+	return old, hadOld, true, nil
+}
+
+func (s *Store) Get(key string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeString)
+	if err != nil || v == nil {
+		return "", false, err
+	}
+	return v.stringValue(), true, nil
+}
+
+// GetSet sets key to val and returns the string it held before.
+func (s *Store) GetSet(key, val string) (string, bool, error) {
+	old, ok, _, err := s.SetWith(key, val, SetOptions{Get: true})
+	return old, ok, err
+}
+
+func (s *Store) GetDel(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeString)
+	if err != nil || v == nil {
+		return "", false, err
+	}
+	s.remove(key)
+	s.modified(key)
+	return v.stringValue(), true, nil
+}
+
+// GetEx returns the string at key and sets its TTL to expire at at, or
+// removes the TTL when persist is set. With neither it is just Get.
+func (s *Store) GetEx(key string, at time.Time, persist bool) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeString)
+	if err != nil || v == nil {
+		return "", false, err
+	}
+	switch {
+	case !at.IsZero():
+		s.expiries.set(key, at)
+		s.modified(key)
+	case persist:
+		if _, ok := s.expiries.get(key); ok {
+			s.expiries.delete(key)
+			s.modified(key)
+		}
+	}
+	return v.stringValue(), true, nil
+}
+
+// IncrBy adds delta to the integer stored at key, a missing key counting as
+// 0, and returns the new value. The TTL is kept.
+func (s *Store) IncrBy(key string, delta int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeString)
+	if err != nil {
+		return 0, err
+	}
+	var cur int64
+	if v != nil {
+		n, ok := v.intValue()
+		if !ok {
+			return 0, ErrNotInteger
+		}
+		cur = n
+	}
+	if (delta > 0 && cur > math.MaxInt64-delta) || (delta < 0 && cur < math.MinInt64-delta) {
+		return 0, ErrOverflow
+	}
+
+	if v == nil {
+		v = &value{typ: TypeString}
+		s.add(key, v)
+	}
+	v.str, v.num, v.isInt = "", cur+delta, true
+	s.modified(key)
+	return v.num, nil
+}
+
+// IncrByFloat adds incr to the number stored at key and returns the new
+// value as it is now stored.
+func (s *Store) IncrByFloat(key string, incr float64) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeString)
+	if err != nil {
+		return "", err
+	}
+	var cur float64
+	if v != nil {
+		if v.isInt {
+			cur = float64(v.num)
+		} else if cur, err = strconv.ParseFloat(v.str, 64); err != nil || math.IsNaN(cur) || math.IsInf(cur, 0) {
+			return "", ErrNotFloat
+		}
+	}
+	result := cur + incr
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return "", ErrNaNOrInfinity
+	}
+
+	if v == nil {
+		v = &value{typ: TypeString}
+		s.add(key, v)
+	}
+	v.setStr(strconv.FormatFloat(result, 'f', -1, 64))
+	s.modified(key)
+	return v.str, nil
+}
+
+// MSetNX sets every key to its value only if none of the keys exist. pairs
+// alternates keys and values.
+func (s *Store) MSetNX(pairs ...string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < len(pairs); i += 2 {
+		if s.lookup(pairs[i]) != nil {
+			return false
+		}
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		s.setString(pairs[i], pairs[i+1], false)
+	}
+	return true
+}
+
+// Append appends val to the string at key, creating it if needed, and
+// returns the new length.
+func (s *Store) Append(key, val string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeString)
+	if err != nil {
+		return 0, err
+	}
+	if v == nil {
+		s.setString(key, val, false)
+		return len(val), nil
+	}
+	cur := v.stringValue()
+	if len(cur)+len(val) > MaxStringLength {
+		return 0, ErrStringTooLong
+	}
+	v.setStr(cur + val)
+	s.modified(key)
+	return len(v.str), nil
+}
+
+func (s *Store) StrLen(key string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeString)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return len(v.stringValue()), nil
+}
+
+// GetRange returns the bytes between start and end inclusive, negative
+// offsets counting back from the end of the string.
+func (s *Store) GetRange(key string, start, end int) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeString)
+	if err != nil || v == nil {
+		return "", err
+	}
+	str := v.stringValue()
+	start, end, ok := listRange(len(str), start, end)
+	if !ok {
+		return "", nil
+	}
+	return str[start : end+1], nil
+}
+
+// SetRange overwrites the string at key starting at offset, padding with
+// zero bytes if the string is shorter, and returns the new length.
+func (s *Store) SetRange(key string, offset int, val string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeString)
+	if err != nil {
+		return 0, err
+	}
+	cur := ""
+	if v != nil {
+		cur = v.stringValue()
+	}
+	if len(val) == 0 {
+		return len(cur), nil
+	}
+	if offset > MaxStringLength-len(val) {
+		return 0, ErrStringTooLong
+	}
+
+	buf := []byte(cur)
+	if end := offset + len(val); end > len(buf) {
+		buf = append(buf, make([]byte, end-len(buf))...)
+	}
+	copy(buf[offset:], val)
+	if v == nil {
+		s.setString(key, string(buf), false)
+	} else {
+		v.setStr(string(buf))
+		s.modified(key)
+	}
+	return len(buf), nil
+}
+
+var (
+	ErrLCSNotString = errors.New("The specified keys must contain string values")
+	ErrLCSTooLong   = errors.New("Insufficient memory, transient memory for LCS exceeds the limit")
+)
+
+// maxLCSCells bounds the table LCS fills in, one cell per pair of prefixes
+// of the two strings, to 128MB.
+const maxLCSCells = 1 << 25
+
+// LCSMatch is a range of the longest common subsequence found in both
+// strings, as inclusive byte offsets into each.
+type LCSMatch struct {
+	A, B [2]int
+	Len  int
+}
+
+// LCS returns the longest common subsequence of the strings at two keys,
+// missing keys counting as empty strings, together with the matching
+// ranges from the end of the strings backwards.
+func (s *Store) LCS(key1, key2 string) (string, []LCSMatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var strs [2]string
+	for i, key := range []string{key1, key2} {
+		v := s.lookup(key)
+		if v != nil && v.typ != TypeString {
+			return "", nil, ErrLCSNotString
+		}
+		if v != nil {
+			strs[i] = v.stringValue()
+		}
+	}
+	if len(strs[0])+1 > maxLCSCells/(len(strs[1])+1) {
+		return "", nil, ErrLCSTooLong
+	}
+	lcs, matches := longestCommonSubsequence(strs[0], strs[1])
+	return lcs, matches, nil
+}
+
+func longestCommonSubsequence(a, b string) (string, []LCSMatch) {
+	// dp[i][j] is the LCS length of a[:i] and b[:j].
+	width := len(b) + 1
+	dp := make([]uint32, (len(a)+1)*width)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				dp[i*width+j] = dp[(i-1)*width+j-1] + 1
+			} else {
+				dp[i*width+j] = max(dp[(i-1)*width+j], dp[i*width+j-1])
+			}
+		}
+	}
+
+	lcs := make([]byte, dp[len(a)*width+len(b)])
+	var matches []LCSMatch
+	var cur *LCSMatch
+	for i, j, k := len(a), len(b), len(lcs); i > 0 && j > 0; {
+		switch {
+		case a[i-1] == b[j-1]:
+			k--
+			lcs[k] = a[i-1]
+			i, j = i-1, j-1
+			if cur != nil && cur.A[0] == i+1 && cur.B[0] == j+1 {
+				cur.A[0], cur.B[0] = i, j
+				cur.Len++
+			} else {
+				matches = append(matches, LCSMatch{A: [2]int{i, i}, B: [2]int{j, j}, Len: 1})
+				cur = &matches[len(matches)-1]
+			}
+		case dp[(i-1)*width+j] > dp[i*width+j-1]:
+			i--
+			cur = nil
+		default:
+			j--
+			cur = nil
+		}
+	}
+	return string(lcs), matches
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestIncrByRejectsNonCanonicalIntegers(t *testing.T) {
+	s := NewStore()
+	for _, val := range []string{"01", "+1", " 1", "1.0", "-0", "abc", ""} {
+		s.Set("k", val)
+		if _, err := s.IncrBy("k", 1); !errors.Is(err, ErrNotInteger) {
+			t.Errorf("IncrBy on %q returned %v, want ErrNotInteger", val, err)
+		}
+	}
+}
+
+func TestIncrByOverflowLeavesValue(t *testing.T) {
+	s := NewStore()
+	s.Set("k", "-9223372036854775807")
+
+	if n, err := s.IncrBy("k", -1); err != nil || n != math.MinInt64 {
+		t.Fatalf("IncrBy = %d, %v", n, err)
+	}
+	if _, err := s.IncrBy("k", -1); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("IncrBy past MinInt64 returned %v, want ErrOverflow", err)
+	}
+	if val, _, _ := s.Get("k"); val != "-9223372036854775808" {
+		t.Fatalf("value after overflow is %q", val)
+	}
+
+	// Integer encoded values behave as strings everywhere else.
+	if n, _ := s.Append("k", "0"); n != 21 {
+		t.Fatalf("Append returned %d, want 21", n)
+	}
+	if _, err := s.IncrBy("k", 1); !errors.Is(err, ErrNotInteger) {
+		t.Fatalf("IncrBy on an out of range integer returned %v", err)
+	}
+}
+
+func TestSetRange(t *testing.T) {
+	s := NewStore()
+	s.Set("k", "hello")
+	if n, err := s.SetRange("k", 7, "x"); err != nil || n != 8 {
+		t.Fatalf("SetRange = %d, %v", n, err)
+	}
+	if val, _, _ := s.Get("k"); val != "hello\x00\x00x" {
+		t.Fatalf("value after padding is %q", val)
+	}
+
+	for _, offset := range []int{MaxStringLength, math.MaxInt} {
+		if _, err := s.SetRange("new", offset, "x"); !errors.Is(err, ErrStringTooLong) {
+			t.Errorf("SetRange at %d returned %v, want ErrStringTooLong", offset, err)
+		}
+	}
+	if _, ok, _ := s.Get("new"); ok {
+		t.Fatal("a rejected SetRange created the key")
+	}
+}
+
+func TestLCSMatches(t *testing.T) {
+	s := NewStore()
+	s.Set("a", "ohmytext")
+	s.Set("b", "mynewtext")
+
+	lcs, matches, err := s.LCS("a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lcs != "mytext" {
+		t.Fatalf("LCS = %q, want %q", lcs, "mytext")
+	}
+	want := []LCSMatch{
+		{A: [2]int{4, 7}, B: [2]int{5, 8}, Len: 4},
+		{A: [2]int{2, 3}, B: [2]int{0, 1}, Len: 2},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("got %d matches, want %d", len(matches), len(want))
+	}
+	for i := range want {
+		if matches[i] != want[i] {
+			t.Errorf("match %d = %+v, want %+v", i, matches[i], want[i])
+		}
+	}
+}
+
+func TestLCSRejectsHugeTables(t *testing.T) {
+	s := NewStore()
+	s.Set("a", strings.Repeat("a", 6000))
+	s.Set("b", strings.Repeat("b", 6000))
+	if _, _, err := s.LCS("a", "b"); !errors.Is(err, ErrLCSTooLong) {
+		t.Fatalf("LCS of two 6000 byte strings: err = %v, want %v", err, ErrLCSTooLong)
+	}
+	// A short string may be compared against a long one.
+	s.Set("c", "xaxb")
+	lcs, _, err := s.LCS("a", "c")
+	if err != nil || lcs != "a" {
+		t.Fatalf("LCS = %q, %v, want %q", lcs, err, "a")
+	}
+}
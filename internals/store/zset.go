@@ -0,0 +1,411 @@
+package store
+
+import (
+	"errors"
+	"maps"
+	"math"
+)
+
+var ErrNotANumber = errors.New("resulting score is not a number (NaN)")
+
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// zset is a sorted set: the dict gives O(1) score lookups by member and the
+// skiplist keeps members ordered for rank and range queries.
+type zset struct {
+	dict  map[string]float64
+	zsl   *skiplist
+	index *scanTable
+}
+
+func newZSet() *zset {
+	return &zset{dict: make(map[string]float64), zsl: newSkiplist()}
+}
+
+func (z *zset) set(member string, score float64) {
+	cur, exists := z.dict[member]
+	if exists {
+		if cur == score {
+			return
+		}
+		z.zsl.delete(cur, member)
+	}
+	z.dict[member] = score
+	z.zsl.insert(score, member)
+	if !exists {
+		indexAdd(&z.index, member, len(z.dict), maps.Keys(z.dict))
+	}
+}
+
+func (z *zset) remove(member string) bool {
+	score, exists := z.dict[member]
+	if !exists {
+		return false
+	}
+	delete(z.dict, member)
+	z.zsl.delete(score, member)
+	indexRemove(z.index, member)
+	return true
+}
+
+type ZAddOptions struct {
+	NX, XX, GT, LT bool
+}
+
+// allows reports whether a member currently at cur (if exists) may be set to
+// score under the options.
+func (o ZAddOptions) allows(cur float64, exists bool, score float64) bool {
+	if exists {
+		if o.NX {
+			return false
+		}
+		if o.GT && score <= cur {
+			return false
+		}
+		if o.LT && score >= cur {
+			return false
+		}
+		return true
+	}
+	return !o.XX
+}
+
+// ZAdd sets the score of each member, returning how many members were added
+// and how many existing members had their score changed.
+func (s *Store) ZAdd(key string, members []ZMember, opts ZAddOptions) (added, updated int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeZSet)
+	if err != nil {
+		return 0, 0, err
+	}
+	if v == nil {
+		if opts.XX {
+			return 0, 0, nil
+		}
+		v = &value{typ: TypeZSet, zset: newZSet()}
+		s.add(key, v)
+	}
+
+	for _, m := range members {
+		cur, exists := v.zset.dict[m.Member]
+		if !opts.allows(cur, exists, m.Score) {
+			continue
+		}
+		if !exists {
+			added++
+		} else if cur != m.Score {
+			updated++
+		}
+		v.zset.set(m.Member, m.Score)
+	}
+
+	s.removeIfEmpty(key, v)
+	if added+updated > 0 {
+		s.modified(key)
+	}
+	return added, updated, nil
+}
+
+// ZIncrBy adds incr to the score of member. ok is false when the options
+// prevented the update.
+func (s *Store) ZIncrBy(key, member string, incr float64, opts ZAddOptions) (score float64, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeZSet)
+	if err != nil {
+		return 0, false, err
+	}
+	if v == nil {
+		if opts.XX {
+			return 0, false, nil
+		}
+		v = &value{typ: TypeZSet, zset: newZSet()}
+		s.add(key, v)
+	}
+
+	cur, exists := v.zset.dict[member]
+	score = cur + incr
+	if math.IsNaN(score) {
+		s.removeIfEmpty(key, v)
+		return 0, false, ErrNotANumber
+	}
+	if !opts.allows(cur, exists, score) {
+		s.removeIfEmpty(key, v)
+		return 0, false, nil
+	}
+
+	v.zset.set(member, score)
+	s.modified(key)
+	return score, true, nil
+}
+
+func (s *Store) ZRem(key string, members ...string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeZSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, m := range members {
+		if v.zset.remove(m) {
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.removeIfEmpty(key, v)
+		s.modified(key)
+	}
+	return removed, nil
+}
+
+func (s *Store) ZScore(key, member string) (float64, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeZSet)
+	if err != nil || v == nil {
+		return 0, false, err
+	}
+	score, ok := v.zset.dict[member]
+	return score, ok, nil
+}
+
+func (s *Store) ZCard(key string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeZSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return len(v.zset.dict), nil
+}
+
+// ZRank returns the 0-based rank of member, counted from the highest score
+// when rev is set.
+func (s *Store) ZRank(key, member string, rev bool) (rank int, score float64, ok bool, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeZSet)
+	if err != nil || v == nil {
+		return 0, 0, false, err
+	}
+	score, exists := v.zset.dict[member]
+	if !exists {
+		return 0, 0, false, nil
+	}
+	rank = v.zset.zsl.rank(score, member)
+	if rev {
+		return v.zset.zsl.length - rank, score, true, nil
+	}
+	return rank - 1, score, true, nil
+}
+
+func (s *Store) ZCount(key string, r ScoreRange) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeZSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+
+	zsl := v.zset.zsl
+	first := zsl.firstInRange(r)
+	if first == nil {
+		return 0, nil
+	}
+	last := zsl.lastInRange(r)
+	return zsl.rank(last.score, last.member) - zsl.rank(first.score, first.member) + 1, nil
+}
+
+type ZRangeBy int
+
+const (
+	ZRangeByRank ZRangeBy = iota
+	ZRangeByScore
+	ZRangeByLex
+)
+
+// ZRangeSpec selects members for ZRange. Start and Stop are ranks and may be
+// negative to count from the end; Score and Lex are used by their respective
+// modes. Rev walks from the highest score down, and Offset/Count limit the
+// result of score and lex ranges, a negative Count meaning no limit.
+type ZRangeSpec struct {
+	By     ZRangeBy
+	Start  int
+	Stop   int
+	Score  ScoreRange
+	Lex    LexRange
+	Rev    bool
+	Offset int
+	Count  int
+}
+
+func (s *Store) ZRange(key string, spec ZRangeSpec) ([]ZMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, err := s.lookupType(key, TypeZSet)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return v.zset.rangeOf(spec), nil
+}
+
+// ZRangeStore stores the members selected by spec at dst, replacing whatever
+// dst held, and returns how many were stored.
+func (s *Store) ZRangeStore(dst, src string, spec ZRangeSpec) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupType(src, TypeZSet)
+	if err != nil {
+		return 0, err
+	}
+
+	var members []ZMember
+	if v != nil {
+		members = v.zset.rangeOf(spec)
+	}
+
+	s.remove(dst)
+	if len(members) > 0 {
+		z := newZSet()
+		for _, m := range members {
+			z.set(m.Member, m.Score)
+		}
+		s.add(dst, &value{typ: TypeZSet, zset: z})
+	}
+	s.modified(dst)
+	return len(members), nil
+}
+
+// ZPop removes and returns up to count members with the lowest scores, or
+// the highest when max is set.
+func (s *Store) ZPop(key string, count int, max bool) ([]ZMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.lookupWriteType(key, TypeZSet)
+	if err != nil || v == nil || count <= 0 {
+		return nil, err
+	}
+
+	var popped []ZMember
+	for len(popped) < count {
+		x := v.zset.zsl.header.level[0].forward
+		if max {
+			x = v.zset.zsl.tail
+		}
+		if x == nil {
+			break
+		}
+		popped = append(popped, ZMember{Member: x.member, Score: x.score})
+		v.zset.remove(x.member)
+	}
+
+	s.removeIfEmpty(key, v)
+	s.modified(key)
+	return popped, nil
+}
+
+func (z *zset) rangeOf(spec ZRangeSpec) []ZMember {
+	switch spec.By {
+	case ZRangeByScore:
+		return z.rangeByScore(spec)
+	case ZRangeByLex:
+		return z.rangeByLex(spec)
+	default:
+		return z.rangeByRank(spec)
+	}
+}
+
+func (z *zset) rangeByRank(spec ZRangeSpec) []ZMember {
+	length := z.zsl.length
+	start, stop := spec.Start, spec.Stop
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	start = max(start, 0)
+	if start > stop || start >= length {
+		return []ZMember{}
+	}
+	stop = min(stop, length-1)
+
+	result := make([]ZMember, 0, stop-start+1)
+	var x *skiplistNode
+	if spec.Rev {
+		x = z.zsl.byRank(length - start)
+	} else {
+		x = z.zsl.byRank(start + 1)
+	}
+	for i := start; i <= stop && x != nil; i++ {
+		result = append(result, ZMember{Member: x.member, Score: x.score})
+		x = next(x, spec.Rev)
+	}
+	return result
+}
+
+func (z *zset) rangeByScore(spec ZRangeSpec) []ZMember {
+	var x *skiplistNode
+	if spec.Rev {
+		x = z.zsl.lastInRange(spec.Score)
+	} else {
+		x = z.zsl.firstInRange(spec.Score)
+	}
+	return collect(x, spec, func(n *skiplistNode) bool {
+		if spec.Rev {
+			return spec.Score.aboveMin(n.score)
+		}
+		return spec.Score.belowMax(n.score)
+	})
+}
+
+func (z *zset) rangeByLex(spec ZRangeSpec) []ZMember {
+	var x *skiplistNode
+	if spec.Rev {
+		x = z.zsl.lastInLexRange(spec.Lex)
+	} else {
+		x = z.zsl.firstInLexRange(spec.Lex)
+	}
+	return collect(x, spec, func(n *skiplistNode) bool {
+		if spec.Rev {
+			return spec.Lex.aboveMin(n.member)
+		}
+		return spec.Lex.belowMax(n.member)
+	})
+}
+
+// collect walks from x while inRange holds, applying the spec's LIMIT.
+func collect(x *skiplistNode, spec ZRangeSpec, inRange func(*skiplistNode) bool) []ZMember {
+	result := []ZMember{}
+	for offset := spec.Offset; x != nil && offset > 0; offset-- {
+		x = next(x, spec.Rev)
+	}
+	for x != nil && inRange(x) && (spec.Count < 0 || len(result) < spec.Count) {
+		result = append(result, ZMember{Member: x.member, Score: x.score})
+		x = next(x, spec.Rev)
+	}
+	return result
+}
+
+func next(x *skiplistNode, rev bool) *skiplistNode {
+	if rev {
+		return x.backward
+	}
+	return x.level[0].forward
+}
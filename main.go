@@ -1,18 +1,75 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"go_redis/cmd"
+	"go_redis/internals/aof"
+	"go_redis/internals/resp"
+	"go_redis/internals/snapshot"
 	"go_redis/internals/store"
 	"go_redis/server"
+	"io"
+	"io/fs"
 	"log"
 	"time"
 )
 
 func main() {
-	s := store.NewStore()
-	s.StartCleaner(1 * time.Second)
+	appendOnly := flag.Bool("appendonly", false, "log every write command to the append-only file")
+	appendFilename := flag.String("appendfilename", "appendonly.aof", "path of the append-only file")
+	appendFsync := flag.String("appendfsync", "everysec", "AOF fsync policy: always, everysec or no")
+	rewritePercentage := flag.Int("auto-aof-rewrite-percentage", 100, "rewrite the AOF once it grows by this percentage since the last rewrite, 0 disables")
+	rewriteMinSize := flag.Int64("auto-aof-rewrite-min-size", 64<<20, "minimum AOF size in bytes before an automatic rewrite")
+	dbFilename := flag.String("dbfilename", "dump.vkv", "path of the snapshot file written by SAVE and BGSAVE")
+	databases := flag.Int("databases", store.DefaultDatabases, "number of databases clients can SELECT")
+	flag.Parse()
 
-	srv := server.NewServer(":6379", s)
+	if *databases < 1 {
+		log.Fatal("databases must be at least 1")
+	}
+	dbs := store.NewDatabases(*databases)
+	cmd.UseSnapshot(*dbFilename)
+
+	if *appendOnly {
+		policy, err := aof.ParsePolicy(*appendFsync)
+		if err != nil {
+			log.Fatal(err)
+		}
+		a, err := aof.Open(*appendFilename, policy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		a.SetAutoRewrite(*rewritePercentage, *rewriteMinSize)
+
+		db := 0
+		discard := resp.NewWriter(io.Discard)
+		if err := a.Load(func(args []string) {
+			cmd.Execute(args, dbs, &db, discard)
+		}); err != nil {
+			log.Fatal(err)
+		}
+		cmd.UseAOF(a)
+	} else {
+		entries, err := snapshot.Load(*dbFilename)
+		switch {
+		case err == nil:
+			for _, e := range entries {
+				if e.DB >= len(dbs) {
+					log.Fatalf("%s has keys in database %d but only %d databases are configured", *dbFilename, e.DB, len(dbs))
+				}
+				dbs[e.DB].Restore(e)
+			}
+			log.Printf("[snapshot] Loaded %d keys from %s", len(entries), *dbFilename)
+		case !errors.Is(err, fs.ErrNotExist):
+			log.Fatal(err)
+		}
+	}
+
+	dbs.StartCleaner(100 * time.Millisecond)
+
+	srv := server.NewServer(":6379", dbs)
 	if err := srv.Start(); err != nil {
 		log.Fatal(err)
 	}
-}
\ No newline at end of file
+}
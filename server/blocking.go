@@ -0,0 +1,115 @@
+package server
+
+import (
+	"go_redis/cmd"
+	"time"
+)
+
+// blocked is a client parked by a blocking command. Commands it sends in the
+// meantime are queued on the peer and run once it is unblocked.
+type blocked struct {
+	peer  *Peer
+	args  []string
+	block *cmd.Block
+	keys  []dbKey
+	timer *time.Timer
+}
+
+// block parks p on every key of b until one of them receives elements or
+// the timeout fires, the event loop keeps serving other clients meanwhile.
+// Clients blocked on the same key are served in the order they blocked.
+func (srv *Server) block(p *Peer, args []string, b *cmd.Block) {
+	bc := &blocked{peer: p, args: args, block: b}
+	seen := make(map[string]bool, len(b.Keys))
+	for _, key := range b.Keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		k := dbKey{p.db, key}
+		bc.keys = append(bc.keys, k)
+		srv.blocking[k] = append(srv.blocking[k], bc)
+		srv.dbs[p.db].BlockOn(key)
+	}
+	if b.Timeout > 0 {
+		bc.timer = time.AfterFunc(b.Timeout, func() {
+			srv.timeoutChan <- bc
+		})
+	}
+	p.blocked = bc
+}
+
+// unblock removes bc from every key it is blocked on.
+func (srv *Server) unblock(bc *blocked) {
+	if bc.timer != nil {
+		bc.timer.Stop()
+	}
+	bc.peer.blocked = nil
+	for _, k := range bc.keys {
+		queue := srv.blocking[k]
+		for i, other := range queue {
+			if other == bc {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+		if len(queue) == 0 {
+			delete(srv.blocking, k)
+		} else {
+			srv.blocking[k] = queue
+		}
+		srv.dbs[k.db].UnblockOn(k.key)
+	}
+}
+
+// timeout replies to a client whose blocking command ran out of time. The
+// timer may fire after the client was already served, which is ignored.
+func (srv *Server) timeout(bc *blocked) {
+	if bc.peer.blocked != bc {
+		return
+	}
+	srv.unblock(bc)
+	bc.block.WriteTimeout(bc.peer.out)
+	srv.resume(bc.peer)
+	bc.peer.flush()
+}
+
+// serveReady retries the commands of clients blocked on keys that were
+// pushed to, oldest client first. Serving a client can push to other keys
+// or run its queued commands, so it repeats until no key is ready.
+func (srv *Server) serveReady() {
+	for keys := srv.readyKeys(); len(keys) > 0; keys = srv.readyKeys() {
+		for _, k := range keys {
+			for len(srv.blocking[k]) > 0 {
+				bc := srv.blocking[k][0]
+				if bc.peer.dispatch(bc.args, srv) != nil {
+					break
+				}
+				srv.unblock(bc)
+				srv.resume(bc.peer)
+				bc.peer.flush()
+			}
+		}
+	}
+}
+
+// readyKeys collects the ready keys of every database.
+func (srv *Server) readyKeys() []dbKey {
+	var keys []dbKey
+	for db, s := range srv.dbs {
+		for _, key := range s.ReadyKeys() {
+			keys = append(keys, dbKey{db, key})
+		}
+	}
+	return keys
+}
+
+// resume runs the commands a client sent while it was blocked, stopping
+// early if one of them blocks it again.
+func (srv *Server) resume(p *Peer) {
+	for len(p.pending) > 0 && p.blocked == nil {
+		args := p.pending[0]
+		p.pending = p.pending[1:]
+		p.Handle(args, srv)
+	}
+}
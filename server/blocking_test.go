@@ -0,0 +1,219 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlockedClientsServedInOrder(t *testing.T) {
+	srv, addr := newTestServer(t)
+	a, b, c, pusher := dial(t, addr), dial(t, addr), dial(t, addr), dial(t, addr)
+
+	for i, blocker := range []*client{a, b, c} {
+		blocker.send("BLPOP", "q", "0")
+		waitForBlocked(t, srv, 0, "q", i+1)
+	}
+
+	pusher.call(":2\r\n", "RPUSH", "q", "1", "2")
+	a.expect(bulks("q", "1"))
+	b.expect(bulks("q", "2"))
+	c.expectNothing()
+	waitForBlocked(t, srv, 0, "q", 1)
+
+	pusher.call(":1\r\n", "RPUSH", "q", "3")
+	c.expect(bulks("q", "3"))
+	pusher.call(":0\r\n", "LLEN", "q")
+}
+
+func TestBlockOnSeveralKeys(t *testing.T) {
+	srv, addr := newTestServer(t)
+	a, pusher := dial(t, addr), dial(t, addr)
+
+	a.send("BRPOP", "q1", "q2", "0")
+	waitForBlocked(t, srv, 0, "q2", 1)
+	pusher.call(":2\r\n", "RPUSH", "q2", "x", "y")
+	a.expect(bulks("q2", "y"))
+
+	// Being served on one key unblocks the client from the others.
+	waitForBlocked(t, srv, 0, "q1", 0)
+	pusher.call(":1\r\n", "RPUSH", "q1", "z")
+	a.expectNothing()
+	pusher.call(":1\r\n", "LLEN", "q1")
+}
+
+func TestBlockingIsPerDatabase(t *testing.T) {
+	srv, addr := newTestServer(t)
+	a, pusher := dial(t, addr), dial(t, addr)
+
+	a.call("+Ok\r\n", "SELECT", "1")
+	a.send("BLPOP", "q", "0")
+	waitForBlocked(t, srv, 1, "q", 1)
+
+	pusher.call(":1\r\n", "RPUSH", "q", "db0")
+	a.expectNothing()
+	pusher.call("+Ok\r\n", "SELECT", "1")
+	pusher.call(":1\r\n", "RPUSH", "q", "db1")
+	a.expect(bulks("q", "db1"))
+}
+
+func TestBlockingTimeout(t *testing.T) {
+	srv, addr := newTestServer(t)
+	a, b := dial(t, addr), dial(t, addr)
+
+	start := time.Now()
+	a.call("*-1\r\n", "BLPOP", "q", "0.05")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("BLPOP timed out after %v", elapsed)
+	}
+	waitForBlocked(t, srv, 0, "q", 0)
+
+	// RESP3 clients get a null.
+	b.resp3()
+	b.send("BRPOP", "q", "0.01")
+	b.expect("_\r\n")
+}
+
+// TestBlockedClientQueuesCommands checks that commands a blocked client
+// sends wait until it is served, then run in order.
+func TestBlockedClientQueuesCommands(t *testing.T) {
+	srv, addr := newTestServer(t)
+	a, pusher := dial(t, addr), dial(t, addr)
+
+	a.send("BLPOP", "q", "0")
+	waitForBlocked(t, srv, 0, "q", 1)
+	a.send("SET", "k", "v")
+	a.send("BLPOP", "q", "0")
+	a.send("GET", "k")
+	a.expectNothing()
+	pusher.call("$-1\r\n", "GET", "k")
+
+	// The first push serves the first BLPOP and runs the SET, then the
+	// second BLPOP blocks again, holding back the GET.
+	pusher.call(":1\r\n", "RPUSH", "q", "1")
+	a.expect(bulks("q", "1"))
+	a.expect("+Ok\r\n")
+	a.expectNothing()
+	pusher.call("$1\r\nv\r\n", "GET", "k")
+
+	pusher.call(":1\r\n", "RPUSH", "q", "2")
+	a.expect(bulks("q", "2"))
+	a.expect("$1\r\nv\r\n")
+}
+
+func TestDisconnectUnblocks(t *testing.T) {
+	srv, addr := newTestServer(t)
+	a, b, pusher := dial(t, addr), dial(t, addr), dial(t, addr)
+
+	a.send("BLPOP", "q", "0")
+	waitForBlocked(t, srv, 0, "q", 1)
+	b.send("BLPOP", "q", "0")
+	waitForBlocked(t, srv, 0, "q", 2)
+
+	a.conn.Close()
+	waitForPeers(t, srv, 2)
+	waitForBlocked(t, srv, 0, "q", 1)
+
+	// The element goes to the client still connected, not the one gone.
+	pusher.call(":1\r\n", "RPUSH", "q", "x")
+	b.expect(bulks("q", "x"))
+
+	b.conn.Close()
+	waitForPeers(t, srv, 1)
+	waitForBlocked(t, srv, 0, "q", 0)
+	pusher.call(":1\r\n", "RPUSH", "q", "y")
+	pusher.call(":1\r\n", "LLEN", "q")
+}
+
+func TestBlockingMove(t *testing.T) {
+	srv, addr := newTestServer(t)
+	a, pusher := dial(t, addr), dial(t, addr)
+
+	// Served at once when the source has elements.
+	pusher.call(":2\r\n", "RPUSH", "src", "a", "b")
+	a.call("$1\r\nb\r\n", "BLMOVE", "src", "dst", "RIGHT", "LEFT", "0")
+	a.call("$1\r\na\r\n", "BRPOPLPUSH", "src", "dst", "0")
+	a.call(bulks("a", "b"), "LRANGE", "dst", "0", "-1")
+
+	// Timing out replies with a null bulk string.
+	a.call("$-1\r\n", "BLMOVE", "src", "dst", "LEFT", "LEFT", "0.01")
+	a.call("$-1\r\n", "BRPOPLPUSH", "src", "dst", "0.01")
+	waitForBlocked(t, srv, 0, "src", 0)
+
+	// A push wakes the client, which moves the element.
+	a.send("BLMOVE", "src", "dst", "LEFT", "RIGHT", "0")
+	waitForBlocked(t, srv, 0, "src", 1)
+	pusher.call(":1\r\n", "RPUSH", "src", "c")
+	a.expect("$1\r\nc\r\n")
+	a.send("BRPOPLPUSH", "src", "dst", "0")
+	waitForBlocked(t, srv, 0, "src", 1)
+	pusher.call(":1\r\n", "RPUSH", "src", "d")
+	a.expect("$1\r\nd\r\n")
+	pusher.call(bulks("d", "a", "b", "c"), "LRANGE", "dst", "0", "-1")
+	pusher.call(":0\r\n", "EXISTS", "src")
+}
+
+func TestBLMPop(t *testing.T) {
+	srv, addr := newTestServer(t)
+	a, pusher := dial(t, addr), dial(t, addr)
+
+	pusher.call(":3\r\n", "RPUSH", "l2", "a", "b", "c")
+	a.call("*2\r\n$2\r\nl2\r\n"+bulks("a", "b"), "BLMPOP", "0", "2", "l1", "l2", "LEFT", "COUNT", "2")
+	a.call("*2\r\n$2\r\nl2\r\n"+bulks("c"), "BLMPOP", "0", "2", "l1", "l2", "RIGHT", "COUNT", "5")
+
+	a.call("*-1\r\n", "BLMPOP", "0.01", "2", "l1", "l2", "LEFT")
+	waitForBlocked(t, srv, 0, "l1", 0)
+
+	a.send("BLMPOP", "0", "2", "l1", "l2", "RIGHT", "COUNT", "2")
+	waitForBlocked(t, srv, 0, "l2", 1)
+	pusher.call(":3\r\n", "RPUSH", "l1", "x", "y", "z")
+	a.expect("*2\r\n$2\r\nl1\r\n" + bulks("z", "y"))
+	waitForBlocked(t, srv, 0, "l2", 0)
+	pusher.call(bulks("x"), "LRANGE", "l1", "0", "-1")
+
+	// A numkeys past the arguments given is a syntax error, however large.
+	a.call("-ERR syntax error\r\n", "LMPOP", "9223372036854775807", "a", "LEFT")
+	a.call("-ERR syntax error\r\n", "BLMPOP", "0", "9223372036854775806", "a", "LEFT")
+	a.call("-ERR syntax error\r\n", "BLMPOP", "0", "2", "a", "LEFT")
+	a.call("-ERR numkeys should be greater than 0\r\n", "LMPOP", "0", "a", "LEFT")
+}
+
+// TestBlockingCommandsPropagateNonBlocking checks that the blocking list
+// commands reach the AOF as their non-blocking forms, whether they are
+// served at once or after blocking, and not at all when they time out.
+func TestBlockingCommandsPropagateNonBlocking(t *testing.T) {
+	path := useTestAOF(t)
+	srv, addr := newTestServer(t)
+	a, pusher := dial(t, addr), dial(t, addr)
+
+	pusher.call(":4\r\n", "RPUSH", "src", "a", "b", "c", "d")
+	a.call("$1\r\nd\r\n", "BLMOVE", "src", "dst", "RIGHT", "LEFT", "0")
+	a.call("$1\r\nc\r\n", "BRPOPLPUSH", "src", "dst", "0")
+	a.call("*2\r\n$3\r\nsrc\r\n"+bulks("a", "b"), "BLMPOP", "0", "1", "src", "LEFT", "COUNT", "3")
+
+	a.call("$-1\r\n", "BLMOVE", "src", "dst", "LEFT", "LEFT", "0.01")
+	a.call("$-1\r\n", "BRPOPLPUSH", "src", "dst", "0.01")
+	a.call("*-1\r\n", "BLMPOP", "0.01", "1", "src", "LEFT")
+
+	a.send("BLMOVE", "src", "dst", "LEFT", "RIGHT", "0")
+	waitForBlocked(t, srv, 0, "src", 1)
+	pusher.call(":1\r\n", "RPUSH", "src", "e")
+	a.expect("$1\r\ne\r\n")
+	a.send("BRPOPLPUSH", "src", "dst", "0")
+	waitForBlocked(t, srv, 0, "src", 1)
+	pusher.call(":1\r\n", "RPUSH", "src", "f")
+	a.expect("$1\r\nf\r\n")
+	a.send("BLMPOP", "0", "1", "src", "RIGHT")
+	waitForBlocked(t, srv, 0, "src", 1)
+	pusher.call(":1\r\n", "RPUSH", "src", "g")
+	a.expect("*2\r\n$3\r\nsrc\r\n" + bulks("g"))
+
+	expectAOF(t, path,
+		[]string{"SELECT", "0"}, []string{"RPUSH", "src", "a", "b", "c", "d"},
+		[]string{"LMOVE", "src", "dst", "RIGHT", "LEFT"},
+		[]string{"LMOVE", "src", "dst", "RIGHT", "LEFT"},
+		[]string{"LMPOP", "1", "src", "LEFT", "COUNT", "3"},
+		[]string{"RPUSH", "src", "e"}, []string{"LMOVE", "src", "dst", "LEFT", "RIGHT"},
+		[]string{"RPUSH", "src", "f"}, []string{"LMOVE", "src", "dst", "RIGHT", "LEFT"},
+		[]string{"RPUSH", "src", "g"}, []string{"LMPOP", "1", "src", "RIGHT", "COUNT", "1"},
+	)
+}
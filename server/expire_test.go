@@ -0,0 +1,113 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExpireConditions(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("+Ok\r\n", "SET", "k", "v")
+	c.call(":0\r\n", "EXPIRE", "k", "100", "XX")
+	c.call(":0\r\n", "EXPIRE", "k", "100", "GT")
+	c.call(":1\r\n", "EXPIRE", "k", "100", "NX")
+	c.call(":0\r\n", "EXPIRE", "k", "200", "NX")
+	c.call(":1\r\n", "EXPIRE", "k", "200", "XX")
+	c.call(":0\r\n", "EXPIRE", "k", "50", "GT")
+	c.call(":1\r\n", "EXPIRE", "k", "300", "gt")
+	c.call(":0\r\n", "EXPIRE", "k", "400", "LT")
+	c.call(":1\r\n", "EXPIRE", "k", "10", "LT")
+	c.call(":1\r\n", "EXPIRE", "k", "20", "XX", "GT")
+	c.call(":20\r\n", "TTL", "k")
+	c.call(":0\r\n", "EXPIRE", "missing", "10")
+
+	c.call("-ERR NX and XX, GT or LT options at the same time are not compatible\r\n", "EXPIRE", "k", "10", "NX", "XX")
+	c.call("-ERR NX and XX, GT or LT options at the same time are not compatible\r\n", "EXPIRE", "k", "10", "NX", "GT")
+	c.call("-ERR GT and LT options at the same time are not compatible\r\n", "EXPIRE", "k", "10", "GT", "LT")
+	c.call("-ERR Unsupported option FOO\r\n", "EXPIRE", "k", "10", "FOO")
+	c.call(":20\r\n", "TTL", "k")
+}
+
+func TestExpireTimes(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("+Ok\r\n", "SET", "k", "v")
+	c.call("-ERR value is not an integer or out of range\r\n", "EXPIRE", "k", "soon")
+	c.call("-ERR invalid expire time in 'expire' command\r\n", "EXPIRE", "k", "9223372036854775807")
+	c.call("-ERR invalid expire time in 'expire' command\r\n", "EXPIRE", "k", "-9223372036854775808")
+	c.call("-ERR invalid expire time in 'expireat' command\r\n", "EXPIREAT", "k", "9223372036854775807")
+	c.call("-ERR invalid expire time in 'pexpire' command\r\n", "PEXPIRE", "k", "9223372036854775807")
+	c.call(":-1\r\n", "TTL", "k")
+
+	c.call(":1\r\n", "PEXPIRE", "k", "5000")
+	if ms := c.integer("PTTL", "k"); ms <= 4000 || ms > 5000 {
+		t.Fatalf("PTTL = %d, want about 5000", ms)
+	}
+	c.call(":5\r\n", "TTL", "k")
+
+	at := time.Now().Add(time.Hour).Unix()
+	c.call(":1\r\n", "EXPIREAT", "k", strconv.FormatInt(at, 10))
+	c.call(":"+strconv.FormatInt(at, 10)+"\r\n", "EXPIRETIME", "k")
+	c.call(":"+strconv.FormatInt(at*1000, 10)+"\r\n", "PEXPIRETIME", "k")
+
+	// A time that is not in the future deletes the key.
+	c.call(":1\r\n", "EXPIRE", "k", "-1")
+	c.call("$-1\r\n", "GET", "k")
+	c.call("+Ok\r\n", "SET", "k", "v")
+	c.call(":1\r\n", "PEXPIREAT", "k", "1")
+	c.call(":0\r\n", "EXISTS", "k")
+}
+
+func TestTTLAndPersist(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call(":-2\r\n", "TTL", "missing")
+	c.call(":-2\r\n", "PTTL", "missing")
+	c.call(":-2\r\n", "EXPIRETIME", "missing")
+	c.call("+Ok\r\n", "SET", "k", "v")
+	c.call(":-1\r\n", "TTL", "k")
+	c.call(":-1\r\n", "PTTL", "k")
+	c.call(":-1\r\n", "PEXPIRETIME", "k")
+
+	c.call(":0\r\n", "PERSIST", "k")
+	c.call(":0\r\n", "PERSIST", "missing")
+	c.call(":1\r\n", "EXPIRE", "k", "100")
+	c.call(":1\r\n", "PERSIST", "k")
+	c.call(":-1\r\n", "TTL", "k")
+	c.call("-ERR wrong no. of arguments for 'persist'\r\n", "PERSIST")
+	c.call("-ERR wrong no. of arguments for 'ttl'\r\n", "TTL", "k", "extra")
+
+	c.call("+Ok\r\n", "SET", "short", "v", "PX", "20")
+	time.Sleep(30 * time.Millisecond)
+	c.call(":-2\r\n", "TTL", "short")
+}
+
+func TestExpirePropagatesAsPExpireAt(t *testing.T) {
+	path := useTestAOF(t)
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("+Ok\r\n", "SET", "k", "v")
+	at := time.Now().Add(time.Hour).Unix()
+	c.call(":1\r\n", "EXPIREAT", "k", strconv.FormatInt(at, 10))
+	// Relative times are logged as the absolute time they resolved to.
+	c.call(":1\r\n", "EXPIRE", "k", "7200", "GT")
+	relative := strconv.Itoa(c.integer("PEXPIRETIME", "k"))
+	// Commands whose condition fails change nothing and are not logged.
+	c.call(":0\r\n", "EXPIRE", "k", "10", "GT")
+	c.call(":0\r\n", "EXPIRE", "missing", "10")
+	c.call(":1\r\n", "PERSIST", "k")
+	c.call(":0\r\n", "PERSIST", "k")
+
+	expectAOF(t, path,
+		[]string{"SELECT", "0"}, []string{"SET", "k", "v"},
+		[]string{"PEXPIREAT", "k", strconv.FormatInt(at*1000, 10)},
+		[]string{"PEXPIREAT", "k", relative, "GT"},
+		[]string{"PERSIST", "k"},
+	)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHSetAndHGetAllReplies(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	// HSET replies with how many fields were added, not updated.
+	c.call(":2\r\n", "HSET", "h", "a", "1", "b", "2")
+	c.call(":1\r\n", "HSET", "h", "a", "10", "c", "3")
+	c.call(":0\r\n", "HSET", "h", "a", "11")
+	c.call("-ERR wrong no. of arguments for 'hset'\r\n", "HSET", "h", "a", "1", "b")
+	c.call(":3\r\n", "HLEN", "h")
+
+	// A missing hash is an empty array, not a null.
+	c.call("*0\r\n", "HGETALL", "missing")
+	c.call(":1\r\n", "HSET", "one", "f", "v")
+	c.call(bulks("f", "v"), "HGETALL", "one")
+	c.call("*3\r\n$2\r\n11\r\n$-1\r\n$1\r\n3\r\n", "HMGET", "h", "a", "nope", "c")
+
+	c.call("+Ok\r\n", "SET", "str", "x")
+	c.call("-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", "HSET", "str", "a", "1")
+	c.call("-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", "HGETALL", "str")
+
+	// RESP3 clients get HGETALL as a map.
+	c.resp3()
+	c.call("%1\r\n$1\r\nf\r\n$1\r\nv\r\n", "HGETALL", "one")
+	c.call("%0\r\n", "HGETALL", "missing")
+}
+
+func TestHIncrByReplies(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call(":5\r\n", "HINCRBY", "h", "n", "5")
+	c.call(":2\r\n", "HINCRBY", "h", "n", "-3")
+	c.call("-ERR value is not an integer or out of range\r\n", "HINCRBY", "h", "n", "1.5")
+	c.call(":2\r\n", "HSET", "h", "s", "abc", "max", "9223372036854775807")
+	c.call("-ERR hash value is not an integer\r\n", "HINCRBY", "h", "s", "1")
+	c.call("-ERR increment or decrement would overflow\r\n", "HINCRBY", "h", "max", "1")
+
+	c.call("$3\r\n2.5\r\n", "HINCRBYFLOAT", "h", "n", "0.5")
+	c.call("$4\r\n10.5\r\n", "HINCRBYFLOAT", "h", "f", "10.5")
+	c.call("-ERR value is not a valid float\r\n", "HINCRBYFLOAT", "h", "n", "abc")
+	c.call("-ERR value is not a valid float\r\n", "HINCRBYFLOAT", "h", "n", "inf")
+	c.call("-ERR hash value is not a float\r\n", "HINCRBYFLOAT", "h", "s", "1")
+	c.call("$3\r\n2.5\r\n", "HGET", "h", "n")
+}
+
+func TestHRandFieldReplies(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("$-1\r\n", "HRANDFIELD", "missing")
+	c.call("*0\r\n", "HRANDFIELD", "missing", "3")
+	c.call(":1\r\n", "HSET", "h", "a", "1")
+
+	c.call("$1\r\na\r\n", "HRANDFIELD", "h")
+	c.call(bulks("a"), "HRANDFIELD", "h", "5")
+	c.call(bulks("a", "a", "a"), "HRANDFIELD", "h", "-3")
+	c.call(bulks("a", "1"), "HRANDFIELD", "h", "1", "WITHVALUES")
+	c.call(bulks("a", "1", "a", "1"), "HRANDFIELD", "h", "-2", "withvalues")
+
+	c.call("-ERR syntax error\r\n", "HRANDFIELD", "h", "1", "WITHVAL")
+	c.call("-ERR wrong no. of arguments for 'hrandfield'\r\n", "HRANDFIELD", "h", "1", "WITHVALUES", "x")
+	c.call("-ERR value is not an integer or out of range\r\n", "HRANDFIELD", "h", "many")
+	c.call("-ERR value is out of range\r\n", "HRANDFIELD", "h", "-9223372036854775808")
+
+	// RESP3 clients get each field and value as a pair.
+	c.resp3()
+	c.call("*1\r\n"+bulks("a", "1"), "HRANDFIELD", "h", "1", "WITHVALUES")
+}
+
+func TestHIncrByFloatPropagatesAsHSet(t *testing.T) {
+	path := useTestAOF(t)
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("$3\r\n1.5\r\n", "HINCRBYFLOAT", "h", "f", "1.5")
+	c.call(":1\r\n", "HINCRBY", "h", "n", "1")
+	c.call(":1\r\n", "HDEL", "h", "n")
+	c.call(":0\r\n", "HDEL", "h", "n")
+
+	expectAOF(t, path,
+		[]string{"SELECT", "0"}, []string{"HSET", "h", "f", "1.5"},
+		[]string{"HINCRBY", "h", "n", "1"}, []string{"HDEL", "h", "n"},
+	)
+}
+
+func TestHExpireResults(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call(":2\r\n", "HSET", "h", "a", "1", "b", "2")
+	// -2 for a missing field, 1 when set, 0 when the condition fails.
+	c.call("*2\r\n:1\r\n:-2\r\n", "HEXPIRE", "h", "100", "FIELDS", "2", "a", "missing")
+	c.call("*2\r\n:0\r\n:1\r\n", "HEXPIRE", "h", "200", "NX", "FIELDS", "2", "a", "b")
+	c.call("*2\r\n:0\r\n:1\r\n", "HEXPIRE", "h", "150", "LT", "FIELDS", "2", "a", "b")
+	c.call("*2\r\n:1\r\n:0\r\n", "HEXPIRE", "h", "120", "gt", "fields", "2", "a", "b")
+	c.call("*3\r\n:120\r\n:150\r\n:-2\r\n", "HTTL", "h", "FIELDS", "3", "a", "b", "missing")
+	c.call("*2\r\n:-2\r\n:-2\r\n", "HEXPIRE", "nokey", "100", "FIELDS", "2", "a", "b")
+
+	// 2 when the time has passed, which deletes the field.
+	c.call("*1\r\n:2\r\n", "HPEXPIREAT", "h", "1", "FIELDS", "1", "a")
+	c.call("$-1\r\n", "HGET", "h", "a")
+	c.call("*1\r\n:-2\r\n", "HTTL", "h", "FIELDS", "1", "a")
+
+	// HPERSIST: -2 for a missing field, -1 without an expiry, 1 when removed.
+	c.call(":1\r\n", "HSET", "h", "c", "3")
+	c.call("*3\r\n:1\r\n:-1\r\n:-2\r\n", "HPERSIST", "h", "FIELDS", "3", "b", "c", "a")
+	c.call("*1\r\n:-1\r\n", "HPTTL", "h", "FIELDS", "1", "b")
+}
+
+func TestHExpireFieldsParsing(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+	c.call(":1\r\n", "HSET", "h", "a", "1")
+
+	c.call("-ERR Mandatory argument FIELDS is missing or not at the right position\r\n", "HEXPIRE", "h", "10", "FILEDS", "1", "a")
+	c.call("-ERR Mandatory argument FIELDS is missing or not at the right position\r\n", "HEXPIRE", "h", "10", "NX", "XX", "FIELDS", "1", "a")
+	c.call("-ERR Parameter `numFields` should be greater than 0\r\n", "HEXPIRE", "h", "10", "FIELDS", "0", "a")
+	c.call("-ERR Parameter `numFields` should be greater than 0\r\n", "HTTL", "h", "FIELDS", "x", "a")
+	c.call("-ERR The `numfields` parameter must match the number of arguments\r\n", "HEXPIRE", "h", "10", "FIELDS", "2", "a")
+	c.call("-ERR The `numfields` parameter must match the number of arguments\r\n", "HPERSIST", "h", "FIELDS", "1", "a", "b")
+	c.call("-ERR The `numfields` parameter must match the number of arguments\r\n", "HTTL", "h", "FIELDS", "9223372036854775807", "a")
+	c.call("-ERR invalid expire time, must be >= 0\r\n", "HEXPIRE", "h", "-1", "FIELDS", "1", "a")
+	c.call("-ERR invalid expire time in 'hexpire' command\r\n", "HEXPIRE", "h", "9223372036854775807", "FIELDS", "1", "a")
+	c.call("-ERR wrong no. of arguments for 'hexpire'\r\n", "HEXPIRE", "h", "10", "FIELDS", "1")
+	c.call("*1\r\n:-1\r\n", "HTTL", "h", "FIELDS", "1", "a")
+}
+
+func TestHExpirePropagatesAsHPExpireAt(t *testing.T) {
+	path := useTestAOF(t)
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call(":2\r\n", "HSET", "h", "a", "1", "b", "2")
+	at := time.Now().Add(time.Hour).Unix()
+	c.call("*1\r\n:1\r\n", "HEXPIREAT", "h", strconv.FormatInt(at, 10), "FIELDS", "1", "a")
+	// Relative times are logged as the absolute time they resolved to.
+	before := time.Now().UnixMilli()
+	c.call("*2\r\n:0\r\n:1\r\n", "HPEXPIRE", "h", "5000", "NX", "FIELDS", "2", "a", "b")
+	after := time.Now().UnixMilli()
+	// Nothing changes, so nothing is logged.
+	c.call("*1\r\n:0\r\n", "HEXPIRE", "h", "10", "GT", "FIELDS", "1", "a")
+	c.call("*2\r\n:1\r\n:-2\r\n", "HPERSIST", "h", "FIELDS", "2", "a", "c")
+
+	relative := aofCommands(t, path)[3][2]
+	if ms, _ := strconv.ParseInt(relative, 10, 64); ms < before+5000 || ms > after+5000 {
+		t.Fatalf("HPEXPIRE 5000 logged as HPEXPIREAT %s, want between %d and %d", relative, before+5000, after+5000)
+	}
+	expectAOF(t, path,
+		[]string{"SELECT", "0"}, []string{"HSET", "h", "a", "1", "b", "2"},
+		[]string{"HPEXPIREAT", "h", strconv.FormatInt(at*1000, 10), "FIELDS", "1", "a"},
+		[]string{"HPEXPIREAT", "h", relative, "NX", "FIELDS", "2", "a", "b"},
+		[]string{"HPERSIST", "h", "FIELDS", "2", "a", "c"},
+	)
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"fmt"
+	"go_redis/internals/resp"
+	"strconv"
+	"strings"
+)
+
+// serverVersion is the version HELLO reports.
+const serverVersion = "1.0.0"
+
+// handleHello implements HELLO [protover [AUTH username password]
+// [SETNAME clientname]], switching the protocol the peer is spoken to in and
+// replying with information about the server.
+func (p *Peer) handleHello(args []string) {
+	proto := p.out.Protocol()
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			p.WriteError("Protocol version is not an integer or out of range")
+			return
+		}
+		if n < 2 || n > 3 {
+			p.out.WriteError("NOPROTO unsupported protocol version")
+			return
+		}
+		proto = n
+	}
+
+	name := ""
+	for i := 2; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "AUTH" && i+2 < len(args):
+			// There are no passwords, so only the default user exists and
+			// any password is accepted for it.
+			if args[i+1] != "default" {
+				p.out.WriteError("WRONGPASS invalid username-password pair or user is disabled.")
+				return
+			}
+			i += 2
+		case option == "SETNAME" && i+1 < len(args):
+			name = args[i+1]
+			if strings.ContainsFunc(name, func(r rune) bool { return r <= ' ' || r > '~' }) {
+				p.WriteError("Client names cannot contain spaces, newlines or special characters.")
+				return
+			}
+			i++
+		default:
+			p.WriteError(fmt.Sprintf("Syntax error in HELLO option '%s'", args[i]))
+			return
+		}
+	}
+
+	p.out.SetProtocol(proto)
+	if name != "" {
+		p.name = name
+	}
+	p.out.WriteValue(resp.Value{Typ: "map", Array: []resp.Value{
+		bulk("server"), bulk("voltkv"),
+		bulk("version"), bulk(serverVersion),
+		bulk("proto"), {Typ: "integer", Num: proto},
+		bulk("id"), {Typ: "integer", Num: int(p.id)},
+		bulk("mode"), bulk("standalone"),
+		bulk("role"), bulk("master"),
+		bulk("modules"), {Typ: "array", Array: []resp.Value{}},
+	}})
+}
+
+func bulk(s string) resp.Value {
+	return resp.Value{Typ: "bulk", Bulk: s}
+}
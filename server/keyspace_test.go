@@ -0,0 +1,170 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRenameReplies(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("-ERR no such key\r\n", "RENAME", "missing", "k")
+	c.call("-ERR no such key\r\n", "RENAMENX", "missing", "k")
+	c.call("+Ok\r\n", "SET", "a", "1")
+	c.call("+Ok\r\n", "SET", "b", "2")
+	c.call("+Ok\r\n", "RENAME", "a", "c")
+	c.call(":0\r\n", "RENAMENX", "c", "b")
+	c.call(":1\r\n", "RENAMENX", "c", "d")
+	c.call("$1\r\n1\r\n", "GET", "d")
+	c.call("+Ok\r\n", "RENAME", "d", "b")
+	c.call("$1\r\n1\r\n", "GET", "b")
+	c.call(":0\r\n", "EXISTS", "d")
+}
+
+func TestCopyAndMove(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("+Ok\r\n", "SET", "a", "1")
+	c.call("+Ok\r\n", "SET", "b", "2")
+	c.call(":0\r\n", "COPY", "missing", "x")
+	c.call(":0\r\n", "COPY", "a", "b")
+	c.call(":1\r\n", "COPY", "a", "b", "REPLACE")
+	c.call("$1\r\n1\r\n", "GET", "b")
+	c.call(":1\r\n", "COPY", "a", "a", "db", "1")
+	c.call("+Ok\r\n", "SET", "b", "2")
+	c.call(":0\r\n", "COPY", "b", "a", "DB", "1")
+	c.call(":1\r\n", "COPY", "b", "a", "replace", "DB", "1")
+
+	c.call("-ERR source and destination objects are the same\r\n", "COPY", "a", "a")
+	c.call("-ERR source and destination objects are the same\r\n", "COPY", "a", "a", "DB", "0")
+	c.call("-ERR syntax error\r\n", "COPY", "a", "b", "DB")
+	c.call("-ERR syntax error\r\n", "COPY", "a", "b", "NOW")
+	c.call("-ERR value is not an integer or out of range\r\n", "COPY", "a", "b", "DB", "one")
+	c.call("-ERR DB index is out of range\r\n", "COPY", "a", "b", "DB", "16")
+	c.call("-ERR DB index is out of range\r\n", "COPY", "a", "b", "DB", "-1")
+
+	c.call("+Ok\r\n", "SET", "m", "v")
+	c.call("-ERR source and destination objects are the same\r\n", "MOVE", "m", "0")
+	c.call("-ERR DB index is out of range\r\n", "MOVE", "m", "99")
+	c.call(":1\r\n", "MOVE", "m", "2")
+	c.call(":0\r\n", "MOVE", "m", "2")
+	c.call(":0\r\n", "EXISTS", "m")
+
+	c.call("+Ok\r\n", "SELECT", "1")
+	c.call("$1\r\n2\r\n", "GET", "a")
+	c.call("+Ok\r\n", "SELECT", "2")
+	c.call("$1\r\nv\r\n", "GET", "m")
+	// MOVE does not overwrite a key in the destination.
+	c.call("+Ok\r\n", "SET", "other", "here")
+	c.call("+Ok\r\n", "SELECT", "0")
+	c.call("+Ok\r\n", "SET", "other", "there")
+	c.call(":0\r\n", "MOVE", "other", "2")
+	c.call("$5\r\nthere\r\n", "GET", "other")
+}
+
+func TestFlushAndUnlink(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("+Ok\r\n", "MSET", "a", "1", "b", "2", "c", "3")
+	c.call(":2\r\n", "UNLINK", "a", "b", "missing")
+	c.call("-ERR wrong no. of arguments for 'unlink'\r\n", "UNLINK")
+	c.call("+Ok\r\n", "SELECT", "1")
+	c.call("+Ok\r\n", "SET", "k", "v")
+
+	c.call("-ERR syntax error\r\n", "FLUSHDB", "LATER")
+	c.call("-ERR wrong no. of arguments for 'flushdb'\r\n", "FLUSHDB", "ASYNC", "SYNC")
+	c.call("+Ok\r\n", "FLUSHDB", "async")
+	c.call(":0\r\n", "DBSIZE")
+	c.call("+Ok\r\n", "SELECT", "0")
+	c.call(":1\r\n", "DBSIZE")
+
+	c.call("+Ok\r\n", "SELECT", "3")
+	c.call("+Ok\r\n", "SET", "k", "v")
+	c.call("-ERR syntax error\r\n", "FLUSHALL", "NOW")
+	c.call("+Ok\r\n", "FLUSHALL", "SYNC")
+	c.call(":0\r\n", "DBSIZE")
+	c.call("+Ok\r\n", "SELECT", "0")
+	c.call(":0\r\n", "DBSIZE")
+}
+
+func TestKeyspaceCommandsReachTheAOF(t *testing.T) {
+	path := useTestAOF(t)
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("+Ok\r\n", "SET", "a", "1")
+	c.call("+Ok\r\n", "RENAME", "a", "b")
+	c.call(":1\r\n", "COPY", "b", "c", "DB", "1", "REPLACE")
+	c.call(":1\r\n", "MOVE", "b", "2")
+	// Failed commands change nothing and are not logged.
+	c.call("-ERR no such key\r\n", "RENAME", "a", "b")
+	c.call(":0\r\n", "COPY", "missing", "c")
+	c.call(":0\r\n", "UNLINK", "missing")
+	c.call("+Ok\r\n", "SELECT", "1")
+	c.call(":1\r\n", "UNLINK", "c")
+	c.call("+Ok\r\n", "SET", "d", "4")
+	c.call("+Ok\r\n", "FLUSHDB")
+	c.call("+Ok\r\n", "SET", "e", "5")
+	c.call("+Ok\r\n", "FLUSHALL", "ASYNC")
+
+	expectAOF(t, path,
+		[]string{"SELECT", "0"}, []string{"SET", "a", "1"}, []string{"RENAME", "a", "b"},
+		[]string{"COPY", "b", "c", "DB", "1", "REPLACE"}, []string{"MOVE", "b", "2"},
+		[]string{"SELECT", "1"}, []string{"UNLINK", "c"},
+		[]string{"SET", "d", "4"}, []string{"FLUSHDB"},
+		[]string{"SET", "e", "5"}, []string{"FLUSHALL", "ASYNC"},
+	)
+}
+
+func TestSelectAndSwapDB(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("-ERR DB index is out of range\r\n", "SELECT", "16")
+	c.call("-ERR DB index is out of range\r\n", "SELECT", "-1")
+	c.call("-ERR value is not an integer or out of range\r\n", "SELECT", "one")
+	c.call("-ERR wrong no. of arguments for 'select'\r\n", "SELECT")
+	c.call("+Ok\r\n", "SET", "k", "zero")
+
+	c.call("-ERR invalid first DB index\r\n", "SWAPDB", "a", "1")
+	c.call("-ERR invalid second DB index\r\n", "SWAPDB", "0", "b")
+	c.call("-ERR DB index is out of range\r\n", "SWAPDB", "0", "16")
+	c.call("-ERR DB index is out of range\r\n", "SWAPDB", "-1", "0")
+	c.call("-ERR wrong no. of arguments for 'swapdb'\r\n", "SWAPDB", "0")
+	c.call("$4\r\nzero\r\n", "GET", "k")
+
+	// The connection stays on index 0, which now holds the other database.
+	c.call("+Ok\r\n", "SWAPDB", "0", "15")
+	c.call(":0\r\n", "EXISTS", "k")
+	c.call("+Ok\r\n", "SELECT", "15")
+	c.call("$4\r\nzero\r\n", "GET", "k")
+	c.call("+Ok\r\n", "SWAPDB", "15", "15")
+	c.call("$4\r\nzero\r\n", "GET", "k")
+}
+
+func TestInfoKeyspace(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	info := func(body string) string {
+		return "$" + strconv.Itoa(len(body)) + "\r\n" + body + "\r\n"
+	}
+	// Empty databases are left out.
+	c.call(info("# Keyspace\r\n"), "INFO", "keyspace")
+
+	c.call("+Ok\r\n", "MSET", "a", "1", "b", "2")
+	c.call("+Ok\r\n", "SET", "c", "3", "EX", "100")
+	c.call("+Ok\r\n", "SELECT", "3")
+	c.call("+Ok\r\n", "SET", "d", "4")
+	c.call(info("# Keyspace\r\ndb0:keys=3,expires=1\r\ndb3:keys=1,expires=0\r\n"), "INFO", "KEYSPACE")
+
+	c.call(":1\r\n", "EXPIRE", "d", "100")
+	c.call(":1\r\n", "PERSIST", "d")
+	c.call("+Ok\r\n", "SELECT", "0")
+	c.call(":1\r\n", "DEL", "c")
+	c.call("+Ok\r\n", "SWAPDB", "0", "1")
+	c.call(info("# Keyspace\r\ndb1:keys=2,expires=0\r\ndb3:keys=1,expires=0\r\n"), "INFO", "keyspace")
+}
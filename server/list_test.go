@@ -0,0 +1,82 @@
+package server
+
+import "testing"
+
+func TestListEditing(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	// The X variants only push onto an existing list.
+	c.call(":0\r\n", "LPUSHX", "l", "a")
+	c.call(":0\r\n", "RPUSHX", "l", "a")
+	c.call(":0\r\n", "EXISTS", "l")
+	c.call(":1\r\n", "RPUSH", "l", "b")
+	c.call(":3\r\n", "LPUSHX", "l", "a2", "a1")
+	c.call(":4\r\n", "RPUSHX", "l", "c")
+	c.call(bulks("a1", "a2", "b", "c"), "LRANGE", "l", "0", "-1")
+
+	c.call(":5\r\n", "LINSERT", "l", "BEFORE", "b", "x")
+	c.call(":6\r\n", "LINSERT", "l", "after", "c", "x")
+	c.call(":-1\r\n", "LINSERT", "l", "AFTER", "nope", "y")
+	c.call(":0\r\n", "LINSERT", "missing", "AFTER", "b", "y")
+	c.call("-ERR syntax error\r\n", "LINSERT", "l", "BESIDE", "b", "y")
+	c.call(bulks("a1", "a2", "x", "b", "c", "x"), "LRANGE", "l", "0", "-1")
+
+	c.call("+Ok\r\n", "LSET", "l", "0", "A")
+	c.call("+Ok\r\n", "LSET", "l", "-1", "Z")
+	c.call("-ERR index out of range\r\n", "LSET", "l", "6", "y")
+	c.call("-ERR no such key\r\n", "LSET", "missing", "0", "y")
+	c.call("-ERR value is not an integer or out of range\r\n", "LSET", "l", "first", "y")
+	c.call(bulks("A", "a2", "x", "b", "c", "Z"), "LRANGE", "l", "0", "-1")
+
+	c.call(":5\r\n", "RPUSH", "r", "a", "b", "a", "c", "a")
+	c.call(":1\r\n", "LREM", "r", "-1", "a")
+	c.call(bulks("a", "b", "a", "c"), "LRANGE", "r", "0", "-1")
+	c.call(":2\r\n", "LREM", "r", "0", "a")
+	c.call(":0\r\n", "LREM", "r", "0", "a")
+	c.call("-ERR value is not an integer or out of range\r\n", "LREM", "r", "all", "a")
+	c.call(bulks("b", "c"), "LRANGE", "r", "0", "-1")
+
+	c.call("+Ok\r\n", "LTRIM", "l", "1", "-2")
+	c.call(bulks("a2", "x", "b", "c"), "LRANGE", "l", "0", "-1")
+	c.call("+Ok\r\n", "LTRIM", "l", "-100", "1")
+	c.call(bulks("a2", "x"), "LRANGE", "l", "0", "-1")
+	c.call("-ERR value is not an integer or out of range\r\n", "LTRIM", "l", "0", "end")
+	// Trimming to an empty range removes the key.
+	c.call("+Ok\r\n", "LTRIM", "l", "5", "10")
+	c.call(":0\r\n", "EXISTS", "l")
+
+	c.call("+Ok\r\n", "SET", "str", "v")
+	c.call("-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", "LPUSHX", "str", "a")
+	c.call("-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", "LINSERT", "str", "BEFORE", "a", "b")
+}
+
+func TestLPos(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call(":8\r\n", "RPUSH", "l", "a", "b", "c", "1", "2", "3", "c", "c")
+	c.call(":2\r\n", "LPOS", "l", "c")
+	c.call("$-1\r\n", "LPOS", "l", "nope")
+	c.call("$-1\r\n", "LPOS", "missing", "c")
+	c.call(":6\r\n", "LPOS", "l", "c", "RANK", "2")
+	c.call(":7\r\n", "LPOS", "l", "c", "RANK", "-1")
+	c.call(":2\r\n", "LPOS", "l", "c", "RANK", "-3")
+	c.call("$-1\r\n", "LPOS", "l", "c", "RANK", "4")
+	c.call("*2\r\n:2\r\n:6\r\n", "LPOS", "l", "c", "COUNT", "2")
+	c.call("*3\r\n:2\r\n:6\r\n:7\r\n", "LPOS", "l", "c", "COUNT", "0")
+	c.call("*2\r\n:7\r\n:6\r\n", "LPOS", "l", "c", "RANK", "-1", "COUNT", "2")
+	c.call("*0\r\n", "LPOS", "l", "nope", "COUNT", "1")
+	c.call("*1\r\n:2\r\n", "LPOS", "l", "c", "COUNT", "0", "MAXLEN", "6")
+	c.call("$-1\r\n", "LPOS", "l", "c", "MAXLEN", "2")
+	c.call("*0\r\n", "LPOS", "missing", "c", "COUNT", "1")
+
+	c.call("-ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list\r\n", "LPOS", "l", "c", "RANK", "0")
+	c.call("-ERR value is out of range, value must between -9223372036854775807 and 9223372036854775807\r\n", "LPOS", "l", "c", "RANK", "-9223372036854775808")
+	c.call(":2\r\n", "LPOS", "l", "c", "RANK", "-9223372036854775807", "MAXLEN", "0", "RANK", "1")
+	c.call("-ERR COUNT can't be negative\r\n", "LPOS", "l", "c", "COUNT", "-1")
+	c.call("-ERR MAXLEN can't be negative\r\n", "LPOS", "l", "c", "MAXLEN", "-1")
+	c.call("-ERR value is not an integer or out of range\r\n", "LPOS", "l", "c", "RANK", "first")
+	c.call("-ERR syntax error\r\n", "LPOS", "l", "c", "LIMIT", "1")
+	c.call("-ERR wrong no. of arguments for 'lpos'\r\n", "LPOS", "l", "c", "RANK")
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"go_redis/cmd"
+	"strings"
+)
+
+// transaction is the MULTI state of a peer: the commands queued so far and
+// the versions of the keys it WATCHed. failed is set once a command could
+// not be queued, which makes EXEC discard the transaction.
+type transaction struct {
+	active  bool
+	failed  bool
+	queued  [][]string
+	watched map[dbKey]uint64
+}
+
+// handleTransaction runs transaction related commands and queues everything
+// else while inside MULTI. It reports whether the command was consumed.
+func (p *Peer) handleTransaction(args []string, srv *Server) bool {
+	tx := &p.tx
+
+	switch strings.ToUpper(args[0]) {
+	case "MULTI":
+		if tx.active {
+			p.WriteError("MULTI calls can not be nested")
+			return true
+		}
+		tx.active = true
+		p.WriteOk()
+
+	case "EXEC":
+		if !tx.active {
+			p.WriteError("EXEC without MULTI")
+			return true
+		}
+		p.exec(srv)
+
+	case "DISCARD":
+		if !tx.active {
+			p.WriteError("DISCARD without MULTI")
+			return true
+		}
+		tx.reset()
+		p.unwatchAll(srv)
+		p.WriteOk()
+
+	case "WATCH":
+		if tx.active {
+			p.WriteError("WATCH inside MULTI is not allowed")
+			return true
+		}
+		if len(args) < 2 {
+			p.WriteError("wrong no. of arguments for 'watch'")
+			return true
+		}
+		if tx.watched == nil {
+			tx.watched = make(map[dbKey]uint64)
+		}
+		for _, key := range args[1:] {
+			k := dbKey{p.db, key}
+			if _, ok := tx.watched[k]; !ok {
+				tx.watched[k] = srv.dbs[p.db].Watch(key)
+			}
+		}
+		p.WriteOk()
+
+	case "UNWATCH":
+		p.unwatchAll(srv)
+		p.WriteOk()
+
+	default:
+		if !tx.active {
+			return false
+		}
+		if err := cmd.CheckArity(args); err != nil {
+			tx.failed = true
+			p.WriteError(err.Error())
+			return true
+		}
+		tx.queued = append(tx.queued, args)
+		p.WriteString("QUEUED")
+	}
+	return true
+}
+
+// exec runs the queued commands back to back. The event loop executes one
+// command at a time, so no other client can interleave with them.
+func (p *Peer) exec(srv *Server) {
+	tx := &p.tx
+	queued, failed := tx.queued, tx.failed
+	tx.reset()
+
+	if failed {
+		p.unwatchAll(srv)
+		p.out.WriteError("EXECABORT Transaction discarded because of previous errors.")
+		return
+	}
+
+	aborted := false
+	for k, version := range tx.watched {
+		if srv.dbs[k.db].WatchVersion(k.key) != version {
+			aborted = true
+			break
+		}
+	}
+	p.unwatchAll(srv)
+
+	if aborted {
+		p.out.WriteNullArray()
+		return
+	}
+
+	p.out.WriteArrayLen(len(queued))
+	// Blocking commands never block inside a transaction, they reply as if
+	// their timeout had already elapsed.
+	cmd.Multi()
+	for _, args := range queued {
+		if b := p.dispatch(args, srv); b != nil {
+			b.WriteTimeout(p.out)
+		}
+	}
+	cmd.Exec(srv.dbs)
+}
+
+func (tx *transaction) reset() {
+	tx.active = false
+	tx.failed = false
+	tx.queued = nil
+}
+
+func (p *Peer) unwatchAll(srv *Server) {
+	for k := range p.tx.watched {
+		srv.dbs[k.db].Unwatch(k.key)
+	}
+	p.tx.watched = nil
+}
@@ -0,0 +1,93 @@
+package server
+
+import "testing"
+
+func TestExecRunsQueuedCommands(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("+Ok\r\n", "MULTI")
+	c.call("+QUEUED\r\n", "SET", "k", "1")
+	c.call("+QUEUED\r\n", "INCR", "k")
+	c.call("+QUEUED\r\n", "LPUSH", "k", "x")
+	c.call("+QUEUED\r\n", "GET", "k")
+	// A command failing at run time does not stop the others.
+	c.call("*4\r\n+Ok\r\n:2\r\n-WRONGTYPE Operation against a key holding the wrong kind of value\r\n$1\r\n2\r\n", "EXEC")
+
+	c.call("-ERR EXEC without MULTI\r\n", "EXEC")
+}
+
+func TestDiscard(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("-ERR DISCARD without MULTI\r\n", "DISCARD")
+	c.call("+Ok\r\n", "MULTI")
+	c.call("+QUEUED\r\n", "SET", "k", "v")
+	c.call("+Ok\r\n", "DISCARD")
+	c.call("$-1\r\n", "GET", "k")
+	c.call("-ERR EXEC without MULTI\r\n", "EXEC")
+}
+
+func TestQueueTimeErrorsAbortExec(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("+Ok\r\n", "MULTI")
+	c.call("+QUEUED\r\n", "SET", "k", "v")
+	c.call("-ERR unknown command 'NOSUCH'\r\n", "NOSUCH", "k")
+	c.call("-ERR wrong no. of arguments for 'get'\r\n", "GET")
+	c.call("-EXECABORT Transaction discarded because of previous errors.\r\n", "EXEC")
+	c.call("$-1\r\n", "GET", "k")
+
+	// The next transaction starts clean.
+	c.call("+Ok\r\n", "MULTI")
+	c.call("+QUEUED\r\n", "SET", "k", "v")
+	c.call("*1\r\n+Ok\r\n", "EXEC")
+}
+
+func TestWatchAbortsExec(t *testing.T) {
+	_, addr := newTestServer(t)
+	a, b := dial(t, addr), dial(t, addr)
+
+	a.call("+Ok\r\n", "WATCH", "k")
+	b.call("+Ok\r\n", "SET", "k", "theirs")
+	a.call("+Ok\r\n", "MULTI")
+	a.call("+QUEUED\r\n", "SET", "k", "mine")
+	a.call("*-1\r\n", "EXEC")
+	a.call("$6\r\ntheirs\r\n", "GET", "k")
+
+	// EXEC unwatches, so the next transaction goes through.
+	a.call("+Ok\r\n", "MULTI")
+	a.call("+QUEUED\r\n", "SET", "k", "mine")
+	a.call("*1\r\n+Ok\r\n", "EXEC")
+
+	// WATCH in another database is not touched by writes to this one.
+	a.call("+Ok\r\n", "WATCH", "k")
+	b.call("+Ok\r\n", "SELECT", "1")
+	b.call("+Ok\r\n", "SET", "k", "other db")
+	a.call("+Ok\r\n", "MULTI")
+	a.call("+QUEUED\r\n", "GET", "k")
+	a.call("*1\r\n$4\r\nmine\r\n", "EXEC")
+}
+
+func TestExecIsWrappedInTheAOF(t *testing.T) {
+	path := useTestAOF(t)
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+	c.call("+Ok\r\n", "MULTI")
+	c.call("+QUEUED\r\n", "GET", "k")
+	c.call("+QUEUED\r\n", "SET", "k", "1")
+	c.call("+QUEUED\r\n", "INCR", "k")
+	c.call("*3\r\n$-1\r\n+Ok\r\n:2\r\n", "EXEC")
+	// A transaction that writes nothing leaves no trace.
+	c.call("+Ok\r\n", "MULTI")
+	c.call("+QUEUED\r\n", "GET", "k")
+	c.call("*1\r\n$1\r\n2\r\n", "EXEC")
+	c.call("+Ok\r\n", "SET", "after", "tx")
+
+	expectAOF(t, path,
+		[]string{"SELECT", "0"}, []string{"MULTI"}, []string{"SET", "k", "1"}, []string{"INCR", "k"}, []string{"EXEC"},
+		[]string{"SET", "after", "tx"},
+	)
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bytes"
+	"net"
+	"sync"
+)
+
+// outbox queues what is written to a client and sends it from its own
+// goroutine, so the event loop never waits on a client that reads slowly.
+type outbox struct {
+	conn  net.Conn
+	mu    sync.Mutex
+	ready sync.Cond
+	queue [][]byte
+	// pending counts the bytes queued or being sent.
+	pending int
+	closing bool
+}
+
+func newOutbox(conn net.Conn) *outbox {
+	o := &outbox{conn: conn}
+	o.ready.L = &o.mu
+	go o.run()
+	return o
+}
+
+// Write queues a copy of b without blocking. It fails once the outbox is
+// closed.
+func (o *outbox) Write(b []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closing {
+		return 0, net.ErrClosed
+	}
+	o.queue = append(o.queue, bytes.Clone(b))
+	o.pending += len(b)
+	o.ready.Signal()
+	return len(b), nil
+}
+
+// Pending returns how many bytes are still to be sent.
+func (o *outbox) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.pending
+}
+
+// Close closes the connection once everything queued has been sent.
+func (o *outbox) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closing = true
+	o.ready.Signal()
+}
+
+// Abort drops whatever is queued and closes the connection right away.
+func (o *outbox) Abort() {
+	o.mu.Lock()
+	o.closing = true
+	o.queue = nil
+	o.ready.Signal()
+	o.mu.Unlock()
+	o.conn.Close()
+}
+
+func (o *outbox) run() {
+	o.mu.Lock()
+	for {
+		for len(o.queue) == 0 && !o.closing {
+			o.ready.Wait()
+		}
+		if len(o.queue) == 0 {
+			break
+		}
+		queue := o.queue
+		o.queue = nil
+		o.mu.Unlock()
+
+		sent, failed := 0, false
+		for _, b := range queue {
+			if _, err := o.conn.Write(b); err != nil {
+				failed = true
+				break
+			}
+			sent += len(b)
+		}
+
+		o.mu.Lock()
+		o.pending -= sent
+		if failed {
+			// The client is gone, its read loop will notice too.
+			o.closing = true
+			o.queue = nil
+			o.pending = 0
+		}
+	}
+	o.mu.Unlock()
+	o.conn.Close()
+}
@@ -5,54 +5,141 @@ import (
 	"fmt"
 	"go_redis/cmd"
 	"go_redis/internals/resp"
-	"go_redis/internals/store"
 	"log"
 	"net"
+	"strings"
 )
 
+// maxBatch bounds how many pipelined commands run before their replies
+// are flushed.
+const maxBatch = 1024
+
 type Peer struct {
 	conn    net.Conn
+	outbox  *outbox
 	cmdChan chan Command
 	reader  *resp.Resp
-	writer  *bufio.Writer
+	out     *resp.Writer
+	id      int64
 	name    string
+	closed  bool
+	// db is the database selected with SELECT.
+	db int
+
+	blocked *blocked
+	pending [][]string
+
+	tx       transaction
+	channels map[string]struct{}
+	patterns map[string]struct{}
 }
 
 func NewPeer(conn net.Conn, cmdChan chan Command) *Peer {
+	outbox := newOutbox(conn)
 	return &Peer{
 		conn:    conn,
+		outbox:  outbox,
 		cmdChan: cmdChan,
 		reader:  resp.NewResp(bufio.NewReader(conn)),
-		writer:  bufio.NewWriter(conn),
+		out:     resp.NewWriter(outbox),
 		name:    conn.RemoteAddr().String(),
+
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
 	}
 }
 
+// ReadLoop hands the commands read from the client to the event loop. The
+// read error that ends it travels with the last batch, so the event loop
+// replies to every command before closing the connection.
 func (p *Peer) ReadLoop(srv *Server) {
-	defer func() {
-		p.conn.Close()
-		srv.removePeerChan <- p
-	}()
-
 	for {
-		val, err := p.reader.ReadValue()
+		batch, err := p.readBatch()
+		if len(batch) > 0 || err != nil {
+			srv.cmdChan <- Command{Peer: p, Batch: batch, Err: err}
+		}
 		if err != nil {
-			p.WriteError("ERR " + err.Error())
 			return
 		}
-		cmd := Command{
-			Peer: p,
-			Args: val,
+	}
+}
+
+// readBatch reads the next command along with any pipelined behind it
+// that have already arrived, so their replies can be flushed together.
+func (p *Peer) readBatch() ([]resp.Value, error) {
+	val, err := p.reader.ReadCommand()
+	if err != nil {
+		return nil, err
+	}
+	batch := []resp.Value{val}
+	for len(batch) < maxBatch && p.reader.Buffered() > 0 {
+		val, err := p.reader.ReadCommand()
+		if err != nil {
+			return batch, err
 		}
-		srv.cmdChan <- cmd
+		batch = append(batch, val)
 	}
+	return batch, nil
 }
 
-func (p *Peer) Handle(args []string, s *store.Store) {
+func (p *Peer) Handle(args []string, srv *Server) {
+	if p.closed {
+		return
+	}
+	if p.blocked != nil {
+		p.pending = append(p.pending, args)
+		return
+	}
 	log.Printf("[Peer %s] Executing command: %v", p.name, args)
-	cmd.Execute(args, s, p.conn)
+
+	name := strings.ToUpper(args[0])
+	if p.subscriptions() > 0 && p.out.Protocol() < 3 && !allowedWhileSubscribed(name) {
+		p.WriteError(fmt.Sprintf("Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", strings.ToLower(name)))
+		return
+	}
+	if p.handleTransaction(args, srv) {
+		return
+	}
+	if b := p.dispatch(args, srv); b != nil {
+		srv.block(p, args, b)
+	}
+}
+
+// dispatch runs a single command, either one handled at the connection
+// level or one executed against the store. It returns the Block of a
+// blocking command that could not be served yet.
+func (p *Peer) dispatch(args []string, srv *Server) *cmd.Block {
+	switch strings.ToUpper(args[0]) {
+	case "QUIT":
+		p.WriteOk()
+		p.flush()
+		p.closed = true
+		p.outbox.Close()
+		return nil
+	case "HELLO":
+		p.handleHello(args)
+		return nil
+	}
+	if p.handlePubSub(args, srv) {
+		return nil
+	}
+	return cmd.Execute(args, srv.dbs, &p.db, p.out)
+}
+
+// flush hands the replies buffered for the client to its outbox.
+func (p *Peer) flush() {
+	p.out.Flush()
 }
 
 func (p *Peer) WriteError(message string) {
-	fmt.Fprintf(p.conn, "-ERR %s\r\n", message)
+	p.out.WriteError("ERR " + message)
+}
+
+// WriteOk replies the way commands executed against the store do.
+func (p *Peer) WriteOk() {
+	cmd.WriteOk(p.out)
+}
+
+func (p *Peer) WriteString(s string) {
+	p.out.WriteSimpleString(s)
 }
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"fmt"
+	"go_redis/internals/glob"
+	"go_redis/internals/resp"
+	"log"
+	"sort"
+	"strings"
+)
+
+// maxPushBacklog bounds how much a subscriber may leave unread, like
+// Redis' pubsub client-output-buffer-limit, before it is disconnected.
+const maxPushBacklog = 32 << 20
+
+// pubsub tracks which peers are subscribed to which channels and patterns.
+// It is only used from the event loop.
+type pubsub struct {
+	channels map[string]map[*Peer]struct{}
+	patterns map[string]map[*Peer]struct{}
+}
+
+func newPubSub() *pubsub {
+	return &pubsub{
+		channels: make(map[string]map[*Peer]struct{}),
+		patterns: make(map[string]map[*Peer]struct{}),
+	}
+}
+
+func (ps *pubsub) subscribe(p *Peer, channel string) {
+	if _, ok := p.channels[channel]; ok {
+		return
+	}
+	p.channels[channel] = struct{}{}
+	if ps.channels[channel] == nil {
+		ps.channels[channel] = make(map[*Peer]struct{})
+	}
+	ps.channels[channel][p] = struct{}{}
+}
+
+func (ps *pubsub) unsubscribe(p *Peer, channel string) {
+	delete(p.channels, channel)
+	delete(ps.channels[channel], p)
+	if len(ps.channels[channel]) == 0 {
+		delete(ps.channels, channel)
+	}
+}
+
+func (ps *pubsub) psubscribe(p *Peer, pattern string) {
+	if _, ok := p.patterns[pattern]; ok {
+		return
+	}
+	p.patterns[pattern] = struct{}{}
+	if ps.patterns[pattern] == nil {
+		ps.patterns[pattern] = make(map[*Peer]struct{})
+	}
+	ps.patterns[pattern][p] = struct{}{}
+}
+
+func (ps *pubsub) punsubscribe(p *Peer, pattern string) {
+	delete(p.patterns, pattern)
+	delete(ps.patterns[pattern], p)
+	if len(ps.patterns[pattern]) == 0 {
+		delete(ps.patterns, pattern)
+	}
+}
+
+func (ps *pubsub) unsubscribeAll(p *Peer) {
+	for channel := range p.channels {
+		ps.unsubscribe(p, channel)
+	}
+	for pattern := range p.patterns {
+		ps.punsubscribe(p, pattern)
+	}
+}
+
+// publish delivers message to every subscriber of channel and every peer with
+// a matching pattern, returning how many deliveries were made.
+func (ps *pubsub) publish(channel, message string) int {
+	receivers := 0
+
+	if subs := ps.channels[channel]; len(subs) > 0 {
+		frames := newFrames("message", channel, message)
+		for p := range subs {
+			if p.push(frames.encode(p.out.Protocol())) {
+				receivers++
+			}
+		}
+	}
+
+	for pattern, subs := range ps.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		frames := newFrames("pmessage", pattern, channel, message)
+		for p := range subs {
+			if p.push(frames.encode(p.out.Protocol())) {
+				receivers++
+			}
+		}
+	}
+	return receivers
+}
+
+func (p *Peer) subscriptions() int {
+	return len(p.channels) + len(p.patterns)
+}
+
+// push queues an unsolicited message for a subscriber, reporting whether it
+// was. A subscriber that cannot keep up is disconnected rather than allowed
+// to pile up messages without bound.
+func (p *Peer) push(frame []byte) bool {
+	if pending := p.outbox.Pending(); pending > maxPushBacklog {
+		log.Printf("[Peer %s] Dropping subscriber with %d bytes unread", p.name, pending)
+		p.outbox.Abort()
+		return false
+	}
+	p.out.Write(frame)
+	return p.out.Flush() == nil
+}
+
+// allowedWhileSubscribed lists the only commands a peer may send once it has
+// subscribed to at least one channel or pattern.
+func allowedWhileSubscribed(name string) bool {
+	switch name {
+	case "SUBSCRIBE", "UNSUBSCRIBE", "PSUBSCRIBE", "PUNSUBSCRIBE", "PING", "QUIT":
+		return true
+	}
+	return false
+}
+
+// handlePubSub runs publish/subscribe commands and reports whether args was
+// one of them.
+func (p *Peer) handlePubSub(args []string, srv *Server) bool {
+	ps := srv.pubsub
+
+	switch strings.ToUpper(args[0]) {
+	case "SUBSCRIBE":
+		if len(args) < 2 {
+			p.WriteError("wrong no. of arguments for 'subscribe'")
+			return true
+		}
+		for _, channel := range args[1:] {
+			ps.subscribe(p, channel)
+			p.out.WriteValue(subscription("subscribe", &channel, p.subscriptions()))
+		}
+
+	case "PSUBSCRIBE":
+		if len(args) < 2 {
+			p.WriteError("wrong no. of arguments for 'psubscribe'")
+			return true
+		}
+		for _, pattern := range args[1:] {
+			ps.psubscribe(p, pattern)
+			p.out.WriteValue(subscription("psubscribe", &pattern, p.subscriptions()))
+		}
+
+	case "UNSUBSCRIBE":
+		channels := args[1:]
+		if len(channels) == 0 {
+			channels = sortedKeys(p.channels)
+		}
+		if len(channels) == 0 {
+			p.out.WriteValue(subscription("unsubscribe", nil, p.subscriptions()))
+		}
+		for _, channel := range channels {
+			ps.unsubscribe(p, channel)
+			p.out.WriteValue(subscription("unsubscribe", &channel, p.subscriptions()))
+		}
+
+	case "PUNSUBSCRIBE":
+		patterns := args[1:]
+		if len(patterns) == 0 {
+			patterns = sortedKeys(p.patterns)
+		}
+		if len(patterns) == 0 {
+			p.out.WriteValue(subscription("punsubscribe", nil, p.subscriptions()))
+		}
+		for _, pattern := range patterns {
+			ps.punsubscribe(p, pattern)
+			p.out.WriteValue(subscription("punsubscribe", &pattern, p.subscriptions()))
+		}
+
+	case "PUBLISH":
+		if len(args) != 3 {
+			p.WriteError("wrong no. of arguments for 'publish'")
+			return true
+		}
+		p.out.WriteInteger(ps.publish(args[1], args[2]))
+
+	case "PUBSUB":
+		p.handlePubSubIntrospection(args, ps)
+
+	case "PING":
+		// RESP3 clients can run any command while subscribed, so their
+		// PING gets the regular reply.
+		if p.subscriptions() == 0 || p.out.Protocol() >= 3 {
+			return false
+		}
+		message := ""
+		if len(args) > 1 {
+			message = args[1]
+		}
+		p.out.WriteBulkStrings([]string{"pong", message})
+
+	default:
+		return false
+	}
+	return true
+}
+
+func (p *Peer) handlePubSubIntrospection(args []string, ps *pubsub) {
+	if len(args) < 2 {
+		p.WriteError("wrong no. of arguments for 'pubsub'")
+		return
+	}
+
+	switch sub := strings.ToUpper(args[1]); sub {
+	case "CHANNELS":
+		if len(args) > 3 {
+			p.WriteError("wrong no. of arguments for 'pubsub|channels'")
+			return
+		}
+		var channels []string
+		for _, channel := range sortedKeys(ps.channels) {
+			if len(args) == 2 || glob.Match(args[2], channel) {
+				channels = append(channels, channel)
+			}
+		}
+		p.out.WriteBulkStrings(channels)
+
+	case "NUMSUB":
+		p.out.WriteArrayLen(2 * len(args[2:]))
+		for _, channel := range args[2:] {
+			p.out.WriteBulkString(channel)
+			p.out.WriteInteger(len(ps.channels[channel]))
+		}
+
+	case "NUMPAT":
+		if len(args) != 2 {
+			p.WriteError("wrong no. of arguments for 'pubsub|numpat'")
+			return
+		}
+		p.out.WriteInteger(len(ps.patterns))
+
+	default:
+		p.WriteError(fmt.Sprintf("unknown subcommand '%s'. Try PUBSUB HELP.", args[1]))
+	}
+}
+
+// frames encodes a pubsub message once per protocol version it is sent in:
+// a push for RESP3 subscribers and an array for RESP2 ones.
+type frames struct {
+	push    resp.Value
+	encoded [2][]byte
+}
+
+func newFrames(items ...string) *frames {
+	f := &frames{push: resp.Value{Typ: "push", Array: make([]resp.Value, len(items))}}
+	for i, item := range items {
+		f.push.Array[i] = bulk(item)
+	}
+	return f
+}
+
+func (f *frames) encode(proto int) []byte {
+	i := min(proto, 3) - 2
+	if f.encoded[i] == nil {
+		f.encoded[i] = f.push.Marshal(proto)
+	}
+	return f.encoded[i]
+}
+
+// subscription builds a (un)subscribe confirmation, a nil name is sent as
+// a null.
+func subscription(kind string, name *string, count int) resp.Value {
+	v := resp.Value{Typ: "push", Array: []resp.Value{bulk(kind), {Typ: "null"}, {Typ: "integer", Num: count}}}
+	if name != nil {
+		v.Array[1] = bulk(*name)
+	}
+	return v
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bulks encodes an array of bulk strings.
+func bulks(items ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(items))
+	for _, item := range items {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(item), item)
+	}
+	return b.String()
+}
+
+func confirmation(kind, name string, count int) string {
+	return fmt.Sprintf("*3\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n:%d\r\n", len(kind), kind, len(name), name, count)
+}
+
+func TestPublishReachesChannelAndPatternSubscribers(t *testing.T) {
+	_, addr := newTestServer(t)
+	sub, psub, pub := dial(t, addr), dial(t, addr), dial(t, addr)
+
+	sub.send("SUBSCRIBE", "news.sport", "news.tech")
+	sub.expect(confirmation("subscribe", "news.sport", 1))
+	sub.expect(confirmation("subscribe", "news.tech", 2))
+	psub.call(confirmation("psubscribe", "news.*", 1), "PSUBSCRIBE", "news.*")
+
+	pub.call(":2\r\n", "PUBLISH", "news.sport", "goal")
+	sub.expect(bulks("message", "news.sport", "goal"))
+	psub.expect(bulks("pmessage", "news.*", "news.sport", "goal"))
+
+	pub.call(":0\r\n", "PUBLISH", "weather", "rain")
+	sub.expectNothing()
+	psub.expectNothing()
+
+	sub.call(confirmation("unsubscribe", "news.sport", 1), "UNSUBSCRIBE", "news.sport")
+	pub.call(":1\r\n", "PUBLISH", "news.sport", "again")
+	psub.expect(bulks("pmessage", "news.*", "news.sport", "again"))
+	sub.expectNothing()
+}
+
+func TestSubscribedClientCommands(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call(confirmation("subscribe", "ch", 1), "SUBSCRIBE", "ch")
+	c.call("-ERR Can't execute 'get': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context\r\n", "GET", "k")
+	c.call(bulks("pong", "hi"), "PING", "hi")
+
+	c.call(confirmation("unsubscribe", "ch", 0), "UNSUBSCRIBE")
+	c.call("$-1\r\n", "GET", "k")
+	c.call("+PONG\r\n", "PING")
+}
+
+func TestPubSubIntrospection(t *testing.T) {
+	srv, addr := newTestServer(t)
+	a, b, c := dial(t, addr), dial(t, addr), dial(t, addr)
+
+	a.call(confirmation("subscribe", "news", 1), "SUBSCRIBE", "news")
+	b.send("SUBSCRIBE", "news", "sport")
+	b.expect(confirmation("subscribe", "news", 1))
+	b.expect(confirmation("subscribe", "sport", 2))
+	b.call(confirmation("psubscribe", "n*", 3), "PSUBSCRIBE", "n*")
+
+	c.call(bulks("news", "sport"), "PUBSUB", "CHANNELS")
+	c.call(bulks("news"), "PUBSUB", "CHANNELS", "n*")
+	c.call("*4\r\n$4\r\nnews\r\n:2\r\n$7\r\nmissing\r\n:0\r\n", "PUBSUB", "NUMSUB", "news", "missing")
+	c.call(":1\r\n", "PUBSUB", "NUMPAT")
+
+	// Disconnecting drops every subscription of the client.
+	b.conn.Close()
+	waitForPeers(t, srv, 2)
+	c.call(bulks("news"), "PUBSUB", "CHANNELS")
+	c.call(":0\r\n", "PUBSUB", "NUMPAT")
+}
+
+// TestStalledSubscriberDoesNotBlockPublish checks that a subscriber that
+// never reads neither slows PUBLISH down nor keeps other subscribers from
+// their messages, and is dropped once too much piles up for it.
+func TestStalledSubscriberDoesNotBlockPublish(t *testing.T) {
+	srv, addr := newTestServer(t)
+	stalled, reader, pub := dial(t, addr), dial(t, addr), dial(t, addr)
+
+	stalled.call(confirmation("subscribe", "ch", 1), "SUBSCRIBE", "ch")
+	reader.call(confirmation("subscribe", "ch", 1), "SUBSCRIBE", "ch")
+
+	message := strings.Repeat("x", 1<<20)
+	want := bulks("message", "ch", message)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reader.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		got := make([]byte, len(want))
+		for i := 0; i < 2*maxPushBacklog/len(message); i++ {
+			if _, err := io.ReadFull(reader.r, got); err != nil || string(got) != want {
+				t.Errorf("message %d not delivered: %v", i, err)
+				return
+			}
+		}
+	}()
+
+	var slowest time.Duration
+	for i := 0; i < 2*maxPushBacklog/len(message); i++ {
+		start := time.Now()
+		pub.send("PUBLISH", "ch", message)
+		pub.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, err := pub.r.ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+		slowest = max(slowest, time.Since(start))
+	}
+	if slowest > 500*time.Millisecond {
+		t.Errorf("slowest PUBLISH took %v", slowest)
+	}
+	<-done
+
+	// Dropping the stalled subscriber leaves only the reader.
+	waitForPeers(t, srv, 2)
+	pub.call(":1\r\n", "PUBLISH", "ch", "after")
+	reader.expect(bulks("message", "ch", "after"))
+}
+
+func TestQuitFromSubscribedClient(t *testing.T) {
+	srv, addr := newTestServer(t)
+	sub, pub := dial(t, addr), dial(t, addr)
+
+	sub.call(confirmation("subscribe", "ch", 1), "SUBSCRIBE", "ch")
+	sub.call("+Ok\r\n", "QUIT")
+	sub.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if b, err := sub.r.ReadByte(); err != io.EOF {
+		t.Fatalf("read %q after QUIT, want EOF: %v", b, err)
+	}
+
+	waitForPeers(t, srv, 1)
+	pub.call(":0\r\n", "PUBLISH", "ch", "gone")
+	pub.call("+Ok\r\n", "QUIT")
+}
@@ -1,37 +1,52 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"go_redis/internals/resp"
 	"go_redis/internals/store"
+	"io"
 	"log"
 	"net"
 	"sync"
 )
 
 type Server struct {
-	address        string
-	store          *store.Store
-	cmdChan        chan Command
-	peers          map[*Peer]bool
-	addPeerChan    chan *Peer
-	removePeerChan chan *Peer
-	mu             sync.Mutex
+	address     string
+	dbs         store.Databases
+	cmdChan     chan Command
+	peers       map[*Peer]bool
+	addPeerChan chan *Peer
+	timeoutChan chan *blocked
+	blocking    map[dbKey][]*blocked
+	pubsub      *pubsub
+	nextID      int64
+	mu          sync.Mutex
 }
 
 type Command struct {
-	Peer *Peer
-	Args resp.Value
+	Peer  *Peer
+	Batch []resp.Value
+	// Err is the read error that ended the connection after Batch.
+	Err error
 }
 
-func NewServer(address string, s *store.Store) *Server {
+// dbKey identifies a key in one of the databases.
+type dbKey struct {
+	db  int
+	key string
+}
+
+func NewServer(address string, dbs store.Databases) *Server {
 	return &Server{
-		address:        address,
-		store:          s,
-		cmdChan:        make(chan Command, 100),
-		peers:          make(map[*Peer]bool),
-		addPeerChan:    make(chan *Peer),
-		removePeerChan: make(chan *Peer),
+		address:     address,
+		dbs:         dbs,
+		cmdChan:     make(chan Command, 100),
+		peers:       make(map[*Peer]bool),
+		addPeerChan: make(chan *Peer),
+		timeoutChan: make(chan *blocked),
+		blocking:    make(map[dbKey][]*blocked),
+		pubsub:      newPubSub(),
 	}
 }
 
@@ -41,17 +56,26 @@ func (srv *Server) Start() error {
 		return err
 	}
 	fmt.Println("Server listening on ", srv.address)
+	return srv.Serve(ln)
+}
 
+// Serve runs the event loop and accepts clients on ln until it is closed.
+func (srv *Server) Serve(ln net.Listener) error {
 	go srv.eventLoop()
 
 	for {
 		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return err
+		}
 		if err != nil {
 			log.Printf("Failed to accept connection: %v", err)
 			continue
 		}
 		log.Printf("New connection from %s", conn.RemoteAddr())
 		p := NewPeer(conn, srv.cmdChan)
+		srv.nextID++
+		p.id = srv.nextID
 		srv.addPeerChan <- p
 		log.Printf("Peers %v", srv.peers)
 		go p.ReadLoop(srv)
@@ -67,25 +91,53 @@ func (srv *Server) eventLoop() {
 			srv.mu.Unlock()
 			log.Printf("Added peer: %s", p.name)
 
-		case p := <-srv.removePeerChan:
-			srv.mu.Lock()
-			delete(srv.peers, p)
-			srv.mu.Unlock()
-			log.Printf("Removed peer: %s", p.name)
-
 		case cmd := <-srv.cmdChan:
 			srv.handleConnection(cmd)
+
+		case bc := <-srv.timeoutChan:
+			srv.timeout(bc)
+			srv.serveReady()
 		}
 	}
 }
 
 func (srv *Server) handleConnection(cmd Command) {
-	args := parseArgs(cmd.Args)
-	if len(args) == 0 {
-		cmd.Peer.WriteError("Err invalid command")
+	for _, v := range cmd.Batch {
+		if v.Typ == "array" && len(v.Array) == 0 {
+			// Blank inline lines and empty arrays are ignored.
+			continue
+		}
+		args := parseArgs(v)
+		if len(args) == 0 {
+			cmd.Peer.WriteError("Err invalid command")
+			continue
+		}
+		cmd.Peer.Handle(args, srv)
+		srv.serveReady()
+	}
+	if cmd.Err != nil {
+		if !errors.Is(cmd.Err, io.EOF) {
+			cmd.Peer.WriteError(cmd.Err.Error())
+		}
+		cmd.Peer.flush()
+		cmd.Peer.outbox.Close()
+		srv.removePeer(cmd.Peer)
 		return
 	}
-	cmd.Peer.Handle(args, srv.store)
+	cmd.Peer.flush()
+}
+
+func (srv *Server) removePeer(p *Peer) {
+	srv.mu.Lock()
+	delete(srv.peers, p)
+	srv.mu.Unlock()
+	p.closed = true
+	if p.blocked != nil {
+		srv.unblock(p.blocked)
+	}
+	p.unwatchAll(srv)
+	srv.pubsub.unsubscribeAll(p)
+	log.Printf("Removed peer: %s", p.name)
 }
 
 func parseArgs(value resp.Value) []string {
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"go_redis/cmd"
+	"go_redis/internals/aof"
+	"go_redis/internals/store"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newTestServer serves a fresh set of databases on a random local port.
+func newTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(ln.Addr().String(), store.NewDatabases(store.DefaultDatabases))
+	go srv.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+	return srv, ln.Addr().String()
+}
+
+// waitForPeers waits until the server has removed every disconnected
+// client and n remain.
+func waitForPeers(t *testing.T, srv *Server, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		srv.mu.Lock()
+		count := len(srv.peers)
+		srv.mu.Unlock()
+		if count == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server has %d peers, want %d", count, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitForBlocked waits until n clients are blocked on key in database db.
+func waitForBlocked(t *testing.T, srv *Server, db int, key string, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		count := srv.dbs[db].Blocked(key)
+		if count == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients are blocked on %q, want %d", count, key, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// useTestAOF makes commands append to a fresh AOF for the rest of the test
+// and returns its path.
+func useTestAOF(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	a, err := aof.Open(path, aof.FsyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd.UseAOF(a)
+	t.Cleanup(func() {
+		cmd.UseAOF(nil)
+		a.Close()
+	})
+	return path
+}
+
+// expectAOF checks that the AOF at path holds exactly cmds.
+func expectAOF(t *testing.T, path string, cmds ...[]string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []byte
+	for _, args := range cmds {
+		want = append(want, aof.Encode(args)...)
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("AOF holds %q, want %q", data, want)
+	}
+}
+
+// aofCommands returns the commands logged in the AOF at path.
+func aofCommands(t *testing.T, path string) [][]string {
+	t.Helper()
+	a, err := aof.Open(path, aof.FsyncNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	var cmds [][]string
+	if err := a.Load(func(args []string) { cmds = append(cmds, args) }); err != nil {
+		t.Fatal(err)
+	}
+	return cmds
+}
+
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// send writes a command without waiting for its reply.
+func (c *client) send(args ...string) {
+	c.t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// expect reads the next len(want) bytes of replies and compares them to
+// want, which is raw RESP.
+func (c *client) expect(want string) {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	got := make([]byte, len(want))
+	n, err := io.ReadFull(c.r, got)
+	if err != nil {
+		c.t.Fatalf("expected %q, got %q: %v", want, got[:n], err)
+	}
+	if string(got) != want {
+		c.t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+// expectNothing checks that no reply arrives for a short while.
+func (c *client) expectNothing() {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if b, err := c.r.Peek(1); err == nil {
+		c.t.Fatalf("unexpected reply starting with %q", b)
+	}
+}
+
+func (c *client) call(want string, args ...string) {
+	c.t.Helper()
+	c.send(args...)
+	c.expect(want)
+}
+
+// integer sends a command and returns its integer reply.
+func (c *client) integer(args ...string) int {
+	c.t.Helper()
+	c.send(args...)
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, ":"), "\r\n"))
+	if err != nil || line[0] != ':' {
+		c.t.Fatalf("%v replied %q, want an integer", args, line)
+	}
+	return n
+}
+
+// resp3 switches the client to RESP3, skipping the HELLO reply up to the
+// PONG sent after it.
+func (c *client) resp3() {
+	c.t.Helper()
+	c.send("HELLO", "3")
+	c.send("PING")
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		if line == "+PONG\r\n" {
+			return
+		}
+	}
+}
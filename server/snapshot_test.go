@@ -0,0 +1,90 @@
+package server
+
+import (
+	"go_redis/cmd"
+	"go_redis/internals/snapshot"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestSnapshot makes SAVE and BGSAVE write to a fresh file for the rest
+// of the test and returns its path.
+func useTestSnapshot(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dump.vkv")
+	cmd.UseSnapshot(path)
+	t.Cleanup(func() { cmd.UseSnapshot("") })
+	return path
+}
+
+func TestSaveAndLastSave(t *testing.T) {
+	path := useTestSnapshot(t)
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	c.call("+Ok\r\n", "SET", "k", "v")
+	c.call("-ERR wrong no. of arguments for 'save'\r\n", "SAVE", "now")
+	c.call("-ERR wrong no. of arguments for 'lastsave'\r\n", "LASTSAVE", "x")
+
+	// LASTSAVE has a resolution of a second, so wait for the next one.
+	before := c.integer("LASTSAVE")
+	time.Sleep(time.Until(time.Unix(int64(before)+1, 0)))
+	c.call("+Ok\r\n", "SAVE")
+	if after := c.integer("LASTSAVE"); after <= before {
+		t.Fatalf("LASTSAVE = %d after SAVE, want later than %d", after, before)
+	}
+
+	entries, err := snapshot.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Key != "k" || entries[0].Str != "v" {
+		t.Fatalf("snapshot holds %+v, want k=v", entries)
+	}
+}
+
+func TestBGSaveWhileSaving(t *testing.T) {
+	path := useTestSnapshot(t)
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	// A large value keeps the first save busy while the next commands
+	// arrive in the same write.
+	c.call("+Ok\r\n", "SET", "big", strings.Repeat("x", 16<<20))
+	c.send("BGSAVE")
+	c.send("BGSAVE")
+	c.send("SAVE")
+	c.expect("+Background saving started\r\n")
+	c.expect("-ERR Background save already in progress\r\n")
+	c.expect("-ERR Background save already in progress\r\n")
+	c.call("-ERR wrong no. of arguments for 'bgsave'\r\n", "BGSAVE", "SCHEDULE", "x")
+
+	// SAVE succeeds once the background save is done.
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		c.send("SAVE")
+		c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "+Ok\r\n" {
+			break
+		}
+		if line != "-ERR Background save already in progress\r\n" {
+			t.Fatalf("SAVE replied %q", line)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background save did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	entries, err := snapshot.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Key != "big" {
+		t.Fatalf("snapshot holds %d keys, want big", len(entries))
+	}
+}